@@ -1,123 +1,271 @@
 package ml
 
 import (
+	"context"
 	"fmt"
 	"image"
-	"image/color"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
-	_ "net/http/pprof"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/mattn/go-mjpeg"
 	"github.com/pkg/errors"
 	"github.com/projecthunt/reuseable"
-	"github.com/rs/cors"
 	"gocv.io/x/gocv"
 
 	"github.com/genert/ml/decoder"
 )
 
-var colors = []color.RGBA{
-	{R: 255, G: 255, B: 0},
-	{R: 0, G: 255, B: 0},
-	{R: 0, G: 255, B: 255},
-	{R: 255, G: 0, B: 0},
-}
-
 // Application Main engine
 type Application struct {
-	neuralNetwork *gocv.Net
-	layersNames   []string
-	settings      *AppSettings
+	detector     Detector
+	ownsDetector bool
+	settings     *AppSettings
+	logger       *slog.Logger
+	throttle     *logThrottle
+	router       *mux.Router
+	server       *http.Server
+	tracker      *Tracker
+	zones        []*Zone
+	// Region of interest of detection. Nil means whole frame
+	roi         *ROI
+	classifiers *ClassifierPipeline
 }
 
-func NewApp(settings *AppSettings) (*Application, error) {
-	neuralNet := gocv.ReadNet(settings.NeuralNetworkSettings.DarknetWeights, settings.NeuralNetworkSettings.DarknetCFG)
-	yoloLayersIdx := neuralNet.GetUnconnectedOutLayers()
-	outLayerNames := make([]string, 0, 3)
-
-	for _, idx := range yoloLayersIdx {
-		layer := neuralNet.GetLayer(idx)
-		outLayerNames = append(outLayerNames, layer.GetName())
+// NewApp Creates Application with provided settings and its own detector. Every record of logger is annotated with stream name
+func NewApp(settings *AppSettings, logger *slog.Logger) (*Application, error) {
+	detector, err := NewDetector(&settings.NeuralNetworkSettings)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := neuralNet.SetPreferableBackend(gocv.ParseNetBackend(settings.NeuralNetworkSettings.Backend)); err != nil {
-		return nil, errors.Wrapf(err, "Can't set backend %s", settings.NeuralNetworkSettings.Backend)
+	app, err := NewAppWithDetector(settings, detector, logger)
+	if err != nil {
+		_ = detector.Close()
+		return nil, err
 	}
+	app.ownsDetector = true
+	return app, nil
+}
 
-	if err := neuralNet.SetPreferableTarget(gocv.ParseNetTarget(settings.NeuralNetworkSettings.Target)); err != nil {
-		return nil, errors.Wrapf(err, "Can't set target %s", settings.NeuralNetworkSettings.Target)
+// NewAppWithDetector Creates Application using provided detector, e.g. shared by multiple streams. Detector is not closed by Application.
+// Nil logger means slog.Default()
+func NewAppWithDetector(settings *AppSettings, detector Detector, logger *slog.Logger) (*Application, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	zones, err := NewZones(settings.Zones)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't prepare zones")
+	}
+	var roi *ROI
+	if settings.ROISettings.Enable {
+		roi = NewROI(&settings.ROISettings)
+	}
+	throttle := newLogThrottle(time.Duration(settings.LogSettings.RepeatIntervalSec * float64(time.Second)))
+	classifiers, err := NewClassifierPipeline(&settings.NeuralNetworkSettings, logger, throttle)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't create classifiers")
 	}
 
 	return &Application{
-		neuralNetwork: &neuralNet,
-		layersNames:   outLayerNames,
-		settings:      settings,
+		detector:    detector,
+		settings:    settings,
+		logger:      logger.With("stream", settings.StreamName),
+		throttle:    throttle,
+		router:      mux.NewRouter(),
+		tracker:     NewTracker(&settings.TrackerSettings),
+		roi:         roi,
+		classifiers: classifiers,
+		zones:       zones,
 	}, nil
 }
 
-// StartMJPEGStream Start MJPEG video stream in separate goroutine
-func (app *Application) StartMJPEGStream() *mjpeg.Stream {
-	stream := mjpeg.NewStream()
+// hasRoutes Checks whether any HTTP route has been registered
+func (app *Application) hasRoutes() bool {
+	found := false
+	_ = app.router.Walk(func(*mux.Route, *mux.Router, []*mux.Route) error {
+		found = true
+		return nil
+	})
+	return found
+}
+
+// Run Processes frames until source is exhausted or context is cancelled
+func (app *Application) Run(ctx context.Context) error {
+	settings := app.settings
+	var err error
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 
-	go func() {
-		fmt.Printf("Starting MJPEG on http://localhost:%d\n", app.settings.MjpegSettings.Port)
+	/* Initialize dashboard if needed */
+	stats := NewPipelineStats()
+	if settings.DashboardSettings.Enable {
+		app.RegisterDashboard(stats)
+	}
 
-		router := mux.NewRouter()
-		c := cors.New(cors.Options{
-			AllowedOrigins:   []string{"*"},
-			AllowCredentials: true,
-		})
+	overlay := NewOverlayRenderer(&settings.OverlaySettings, settings.StreamName)
 
-		router.HandleFunc("/", stream.ServeHTTP)
-		http.Handle("/", c.Handler(router))
+	/* Initialize scheduling of inferences if needed */
+	var scheduler *DetectionScheduler
+	if settings.DetectionRateSettings.Enable {
+		scheduler = NewDetectionScheduler(&settings.DetectionRateSettings, app.logger)
+	}
 
-		if err := http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", app.settings.MjpegSettings.Port), nil); err != nil {
-			log.Fatalln(err)
+	/* Initialize fall detection if needed */
+	var fall *FallDetector
+	if settings.FallSettings.Enable {
+		if settings.NeuralNetworkSettings.ModelType != "yolov8-pose" {
+			app.logger.Warn("Fall detection requires pose model. It has been disabled", "model_type", settings.NeuralNetworkSettings.ModelType)
+		} else {
+			fall = NewFallDetector(&settings.FallSettings)
 		}
-	}()
-	return stream
-}
+	}
 
-func (app *Application) Run() error {
-	settings := app.settings
+	/* Initialize motion gating of inference if needed */
+	var motion *MotionDetector
+	var lastDetected []*DetectedObject
+	// Last inference has succeeded, so boxes predicted by tracker or reused on static scene can be trusted
+	lastInferred := false
+	if settings.MotionSettings.Enable {
+		motion = NewMotionDetector(&settings.MotionSettings)
+		defer motion.Close()
+	}
 
-	/* Open imshow() GUI in needed */
-	var window *gocv.Window
-	if settings.MjpegSettings.ImshowEnable {
-		fmt.Println("Press 'ESC' to stop imshow()")
-		window = gocv.NewWindow("ML")
-		window.ResizeWindow(settings.VideoSettings.ReducedWidth, settings.VideoSettings.ReducedHeight)
-		defer window.Close()
+	/* Initialize privacy redaction if needed. Unredacted stream is exposed to authenticated viewers only */
+	var redactor *Redactor
+	unredactedStream := false
+	if settings.RedactionSettings.Enable {
+		unredactedStream = settings.MjpegSettings.Enable && settings.HTTPSettings.Auth.Enable
+		if settings.MjpegSettings.Enable && !unredactedStream {
+			app.logger.Warn("Authentication is disabled. Unredacted stream is not available")
+		}
+		if settings.MjpegSettings.RawEnable {
+			app.logger.Warn("Raw stream is redacted too. Use unredacted stream for original frames")
+		}
+		redactor = NewRedactor(&settings.RedactionSettings, unredactedStream)
 	}
 
-	/* Initialize MJPEG server if needed */
-	var stream *mjpeg.Stream
+	/* Initialize output sinks */
+	var sinks []FrameSink
+	defer func() {
+		for _, sink := range sinks {
+			if err := sink.Close(); err != nil {
+				app.logger.Error("Can't close output sink", "sink", fmt.Sprintf("%T", sink), "error", err)
+			}
+		}
+	}()
+	if settings.MjpegSettings.ImshowEnable {
+		if displayAvailable() {
+			app.logger.Info("Press 'ESC' to stop imshow()")
+			sinks = append(sinks, NewDisplaySink(settings.StreamName, settings.VideoSettings.ReducedWidth, settings.VideoSettings.ReducedHeight, stop))
+		} else {
+			app.logger.Warn("There is no graphical environment. imshow() has been disabled")
+		}
+	}
 	if settings.MjpegSettings.Enable {
-		stream = app.StartMJPEGStream()
+		streamer := NewMJPEGStreamer(&settings.MjpegSettings)
+		if unredactedStream {
+			streamer.EnableUnredacted(settings.RedactionSettings.UnredactedRole)
+		}
+		streamer.RegisterRoutes(app.router)
+		sinks = append(sinks, streamer)
+	}
+	var snapshotter *Snapshotter
+	if settings.SnapshotSettings.Enable {
+		snapshotter, err = NewSnapshotter(&settings.SnapshotSettings, settings.StreamName, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create snapshotter")
+		}
+		snapshotter.RegisterRoutes(app.router)
+		sinks = append(sinks, snapshotter)
+	}
+	if settings.EventRecorderSettings.Enable {
+		recorder, err := NewEventRecorder(&settings.EventRecorderSettings, settings.StreamName, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create event recorder")
+		}
+		sinks = append(sinks, recorder)
+	}
+
+	if settings.RecordingSettings.Enable {
+		recorder, err := NewSegmentRecorder(&settings.RecordingSettings, settings.StreamName, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create segment recorder")
+		}
+		sinks = append(sinks, recorder)
+	}
+	if settings.HLSSettings.Enable {
+		streamer, err := NewHLSStreamer(&settings.HLSSettings, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create HLS streamer")
+		}
+		streamer.RegisterRoutes(app.router)
+		sinks = append(sinks, streamer)
+	}
+	if settings.WebhookSettings.Enable {
+		notifier, err := NewWebhookNotifier(&settings.WebhookSettings, settings.StreamName, app.zones, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create webhook notifier")
+		}
+		notifier.RegisterRoutes(app.router)
+		sinks = append(sinks, notifier)
+	}
+	if settings.DatasetExportSettings.Enable {
+		exporter, err := NewDatasetExporter(&settings.DatasetExportSettings, settings.StreamName, settings.NeuralNetworkSettings.NetClasses, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create dataset exporter")
+		}
+		sinks = append(sinks, exporter)
+	}
+
+	/* Initialize event handlers */
+	var handlers []EventHandler
+	defer func() {
+		for _, handler := range handlers {
+			if err := handler.Close(); err != nil {
+				app.logger.Error("Can't close event handler", "handler", fmt.Sprintf("%T", handler), "error", err)
+			}
+		}
+	}()
+	if settings.EventStoreSettings.Enable {
+		store, err := NewEventStore(&settings.EventStoreSettings, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create event store")
+		}
+		store.RegisterRoutes(app.router)
+		handlers = append(handlers, store)
+	}
+	if settings.MQTTSettings.Enable {
+		publisher, err := NewMQTTPublisher(&settings.MQTTSettings, app.logger)
+		if err != nil {
+			return errors.Wrap(err, "Can't create MQTT publisher")
+		}
+		handlers = append(handlers, publisher)
+	}
+
+	if app.hasRoutes() {
+		app.StartHTTPServer()
+		defer app.stopHTTPServer()
 	}
 
 	/* Setup video streaming source */
 	var videoCapture *gocv.VideoCapture
-	var err error
 	var pc net.PacketConn
 	if app.settings.Source == "webcam" {
-		fmt.Println("Starting to capture webcam")
+		app.logger.Info("Starting to capture webcam", "device_id", app.settings.VideoCaptureDeviceSettings.DeviceID)
 		videoCapture, err = gocv.VideoCaptureDevice(app.settings.VideoCaptureDeviceSettings.DeviceID)
 		if err != nil {
 			return errors.Wrap(err, "Can't open video capture")
 		}
 	} else if app.settings.Source == "video" {
-		fmt.Println("Starting to capture video")
+		app.logger.Info("Starting to capture video")
 		videoCapture, err = gocv.OpenVideoCapture("udp://192.168.1.80:35001")
 		if err != nil {
 			return errors.Wrap(err, "Can't open video capture")
 		}
 	} else if app.settings.Source == "camera" {
-		fmt.Println("Starting to listen for packets")
+		app.logger.Info("Starting to listen for packets", "address", app.settings.CameraSettings.Address, "port", app.settings.CameraSettings.Port)
 		pc, err = reuseable.ListenPacket("udp4", fmt.Sprintf("%s:%d", app.settings.CameraSettings.Address, app.settings.CameraSettings.Port))
 		if err != nil {
 			return errors.Wrap(err, "Can't open video capture")
@@ -126,48 +274,61 @@ func (app *Application) Run() error {
 
 	/* Prepare frame */
 	img := NewFrameData()
+	defer img.Close()
 	buf := make([]byte, 1514)
 
-	d, err := decoder.New(decoder.PixelFormatBGR)
+	d, err := decoder.New(decoder.PixelFormatBGR, app.logger.With("component", "decoder"))
 	if err != nil {
 		return errors.Wrap(err, "failed to create H264 decoder")
 	}
 
 	defer d.Close()
 
-	fmt.Println("Ready to process frames")
+	app.logger.Info("Ready to process frames")
+
+	/* Unblock reading of packets on shutdown */
+	if pc != nil {
+		defer pc.Close()
+		go func() {
+			<-ctx.Done()
+			_ = pc.Close()
+		}()
+	}
 
 	/* Read frames */
-	for {
+	for ctx.Err() == nil {
 		// Grab a frame from video capture if possible
 		if videoCapture != nil {
 			if ok := videoCapture.Read(&img.ImgSource); !ok {
-				fmt.Println("Can't read next frame, stop grabbing...")
+				app.logger.Warn("Can't read next frame, stop grabbing...")
 				break
 			}
 		} else if pc != nil { // Otherwise, read from UDP
 
 			n, _, err := pc.ReadFrom(buf)
 			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
 				return fmt.Errorf("failed to read from buffer: %w", err)
 			}
 
 			if n < 72 {
-				fmt.Println("Empty frame has been loaded. Sleep for 400 ms")
+				app.throttle.Log(app.logger, slog.LevelWarn, "Empty frame has been loaded. Sleep for 400 ms", "bytes", n)
 				time.Sleep(400 * time.Millisecond)
 				continue
 			}
 
-			fmt.Println("Passing data from UDP to decoder")
+			app.logger.Debug("Passing data from UDP to decoder", "bytes", n)
 
 			frame, err := d.Decode(buf[72:n])
 			if err != nil {
-				fmt.Println("Failed to decode frame. Sleep for 400 ms")
+				app.throttle.Log(app.logger, slog.LevelWarn, "Failed to decode frame", "error", err)
 				continue
 			}
 
 			if frame == nil {
-				fmt.Println("Empty frame decoded. Skipping frame rendering")
+				app.throttle.Log(app.logger, slog.LevelWarn, "Empty frame decoded. Skipping frame rendering")
 				continue
 			}
 
@@ -179,86 +340,213 @@ func (app *Application) Run() error {
 
 			img.ImgSource = m
 		}
+		img.Timestamp = time.Now()
 
 		/* Skip empty frame */
 		if img.ImgSource.Empty() {
-			fmt.Println("Empty frame has been detected. Sleep for 400 ms")
+			app.throttle.Log(app.logger, slog.LevelWarn, "Empty frame has been detected. Sleep for 400 ms")
 			time.Sleep(400 * time.Millisecond)
 			continue
 		}
 
 		/* Scale frame if configured */
-		var width, height int
-		if settings.Source == "camera" {
-			width = settings.CameraSettings.ReducedWidth
-			height = settings.CameraSettings.ReducedHeight
-		} else {
-			width = settings.VideoSettings.ReducedWidth
-			height = settings.VideoSettings.ReducedHeight
-		}
-		if err := img.Preprocess(width, height); err != nil {
-			fmt.Printf("Can't preprocess. Error: %s. Sleep for 400ms\n", err.Error())
+		reduced := settings.ReducedSize()
+		if err := img.Preprocess(reduced.X, reduced.Y, app.roi); err != nil {
+			app.throttle.Log(app.logger, slog.LevelError, "Can't preprocess. Sleep for 400ms", "error", err)
 			time.Sleep(400 * time.Millisecond)
 			continue
 		}
 
 		/* YOLOv4 Detection */
-		if settings.NeuralNetworkSettings.Enable {
-			detected := app.performDetectionSequential(img, settings.NeuralNetworkSettings.NetClasses, settings.NeuralNetworkSettings.TargetClasses)
-			if len(detected) != 0 {
+		var detected []*DetectedObject
+		var batch *EventBatch
+		var newTracks []*Track
+		var inference time.Duration
+		img.Inferred = false
+		img.Predicted = false
+		settings.RLock()
+		detectionEnable := settings.NeuralNetworkSettings.Enable
+		targetClasses := settings.NeuralNetworkSettings.TargetClasses
+		settings.RUnlock()
+		if detectionEnable && scheduler != nil && !scheduler.ShouldDetect(img.Timestamp) {
+			// Frame is skipped: move boxes of tracks by their velocity
+			img.ImgScaledCopy.Close()
+			detected = app.tracker.Predict(img.Timestamp)
+			img.Predicted = lastInferred
+			for _, detection := range detected {
+				FixRectForOpenCV(&detection.Rect, img.ImgScaled.Cols(), img.ImgScaled.Rows())
+			}
+		} else if detectionEnable {
+			if motion == nil || motion.ShouldDetect(img.ImgScaled, img.Timestamp) {
+				inferenceStart := time.Now()
+				var detectErr error
+				detected, detectErr = app.performDetectionSequential(img, settings.NeuralNetworkSettings.NetClasses, targetClasses)
+				inference = time.Since(inferenceStart)
+				if detectErr != nil {
+					app.throttle.Log(app.logger, slog.LevelError, "Can't detect objects on provided image. Sleep for 100ms", "error", detectErr)
+					time.Sleep(100 * time.Millisecond)
+				} else {
+					img.Inferred = true
+				}
 				for _, detection := range detected {
-					c := colors[detection.ClassID%len(colors)]
-
-					FixRectForOpenCV(&detection.Rect, settings.CameraSettings.Width, settings.CameraSettings.Height)
-					gocv.Rectangle(&img.ImgScaled, detection.Rect, c, 1)
-					gocv.PutText(&img.ImgScaled, detection.ClassName, image.Pt(detection.Rect.Min.X, detection.Rect.Min.Y), gocv.FontHersheyPlain, 1.0, c, 1)
+					FixRectForOpenCV(&detection.Rect, img.ImgScaled.Cols(), img.ImgScaled.Rows())
+				}
+				app.classifiers.Apply(img, detected)
+				lastDetected = detected
+				lastInferred = img.Inferred
+			} else {
+				// Scene is static: reuse detections of last processed frame
+				img.ImgScaledCopy.Close()
+				detected = lastDetected
+				img.Predicted = lastInferred
+			}
+			update := app.tracker.Update(detected, img.Timestamp)
+			newTracks = update.New
+			frameSize := image.Pt(img.ImgScaled.Cols(), img.ImgScaled.Rows())
+			batch = newEventBatch(settings.StreamName, img.Timestamp, detected, update, app.tracker.Tracks(), app.zones, frameSize)
+			if fall != nil {
+				for _, person := range fall.Update(detected, img.Timestamp, frameSize) {
+					app.logger.Warn("Fall has been detected", "track_id", person.TrackID)
+					batch.Events = append(batch.Events, newObjectEvent(EventFall, settings.StreamName, img.Timestamp, person, app.zones, frameSize))
 				}
 			}
+		} else {
+			img.ImgScaledCopy.Close()
+			if motion != nil {
+				motion.Reset()
+			}
+			lastInferred = false
 		}
-
-		/* Show in window if configured */
-		if settings.MjpegSettings.ImshowEnable {
-			window.IMShow(img.ImgScaled)
-			if window.WaitKey(1) == 27 {
-				break
+		if redactor != nil {
+			if !img.Inferred && !img.Predicted {
+				app.throttle.Log(app.logger, slog.LevelWarn, "There are no trusted detections for frame. Frame is blacked out by redaction", "detection_enable", detectionEnable)
 			}
+			redactor.Apply(img, detected)
 		}
+		overlay.Render(&img.ImgScaled, detected, img.Timestamp)
+		if unredactedStream {
+			overlay.Draw(&img.ImgUnredacted, detected, img.Timestamp)
+		}
+		stats.ObserveFrame(img.Timestamp, inference, detected, newTracks)
 
-		/* Stream as MJPEG if configured */
-		if settings.MjpegSettings.Enable {
-			buf, err := gocv.IMEncode(".jpg", img.ImgScaled)
-			if err != nil {
-				log.Printf("Error while decoding to JPG (mjpeg): %s", err.Error())
-			} else {
-				_ = stream.Update(buf.GetBytes())
+		/* Pass frame to output sinks */
+		for _, sink := range sinks {
+			if err := sink.Consume(img, detected); err != nil {
+				app.throttle.Log(app.logger, slog.LevelError, "Output sink failed to consume frame", "sink", fmt.Sprintf("%T", sink), "error", err)
 			}
 		}
-	}
 
-	// Hard release memory
-	img.Close()
-	app.Close()
-	_ = stream.Close()
+		/* Pass events to event handlers. Sinks have saved snapshots of new tracks already */
+		if batch != nil && len(handlers) != 0 {
+			if snapshotter != nil {
+				for _, event := range batch.Events {
+					if event.Type == EventTrackNew {
+						event.Thumbnail = snapshotter.SavedPath(event.Object.TrackID)
+						event.Frame = snapshotter.SavedFramePath(event.Object.TrackID)
+					}
+				}
+			}
+			for _, handler := range handlers {
+				if err := handler.HandleEvents(batch); err != nil {
+					app.throttle.Log(app.logger, slog.LevelError, "Event handler failed to handle events", "handler", fmt.Sprintf("%T", handler), "error", err)
+				}
+			}
+		}
 
-	if pc != nil {
-		_ = pc.Close()
+		/* Adapt rate of inferences to latency of frames passed to neural network */
+		if scheduler != nil && inference > 0 {
+			scheduler.Observe(time.Since(img.Timestamp))
+			app.tracker.SetInferencePeriod(scheduler.InferencePeriod())
+		}
 	}
 
+	app.logger.Info("Frames processing has been stopped")
 	return nil
 }
 
-func (app *Application) performDetectionSequential(frame *FrameData, netClasses, targetClasses []string) []*DetectedObject {
+func (app *Application) performDetectionSequential(frame *FrameData, netClasses, targetClasses []string) ([]*DetectedObject, error) {
+	if app.settings.TilingSettings.Enable {
+		return app.performDetectionTiled(frame, netClasses, targetClasses)
+	}
 	detectedRects, err := DetectObjects(app, frame.ImgScaledCopy, netClasses, targetClasses...)
+	frame.ImgScaledCopy.Close() // free the memory
 	if err != nil {
-		log.Printf("Can't detect objects on provided image due the error: %s. Sleep for 100ms", err.Error())
-		frame.ImgScaledCopy.Close()
-		time.Sleep(100 * time.Millisecond)
+		return nil, err
 	}
-	frame.ImgScaledCopy.Close() // free the memory
-	return detectedRects
+	for _, detection := range detectedRects {
+		detection.Translate(frame.ROI.Min)
+	}
+	return app.filterROI(frame, detectedRects), nil
+}
+
+// filterROI Discards detections outside of region of interest and inside of exclusion masks
+func (app *Application) filterROI(frame *FrameData, detected []*DetectedObject) []*DetectedObject {
+	if app.roi == nil {
+		return detected
+	}
+	return app.roi.Filter(detected, image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows()))
+}
+
+// DetectImage Detects objects on still image the same way as on frames of stream: image is scaled to size (e.g. AppSettings.ReducedSize, no scaling when size is zero),
+// then region of interest, tiling and second-stage classifiers are applied. Boxes are given in coordinates of provided image
+func (app *Application) DetectImage(img gocv.Mat, size image.Point) ([]*DetectedObject, error) {
+	sourceSize := image.Pt(img.Cols(), img.Rows())
+	if size.X <= 0 || size.Y <= 0 {
+		size = sourceSize
+	}
+	frame := NewFrameData()
+	defer frame.Close()
+	img.CopyTo(&frame.ImgSource)
+	if err := frame.Preprocess(size.X, size.Y, app.roi); err != nil {
+		return nil, errors.Wrap(err, "Can't preprocess image")
+	}
+
+	app.settings.RLock()
+	netClasses := app.settings.NeuralNetworkSettings.NetClasses
+	targetClasses := app.settings.NeuralNetworkSettings.TargetClasses
+	app.settings.RUnlock()
+
+	detected, err := app.performDetectionSequential(frame, netClasses, targetClasses)
+	if err != nil {
+		return nil, err
+	}
+	for _, detection := range detected {
+		FixRectForOpenCV(&detection.Rect, size.X, size.Y)
+	}
+	app.classifiers.Apply(frame, detected)
+	if size != sourceSize {
+		for _, detection := range detected {
+			detection.Scale(size, sourceSize)
+		}
+	}
+	return detected, nil
+}
+
+// performDetectionTiled Detects objects on tiles of source image and maps boxes to scaled image
+func (app *Application) performDetectionTiled(frame *FrameData, netClasses, targetClasses []string) ([]*DetectedObject, error) {
+	frame.ImgScaledCopy.Close() // not needed: tiles are taken from source image
+	src, bounds := frame.ImgSource, image.Rectangle{}
+	if app.roi != nil {
+		src, bounds = app.roi.Crop(frame.ImgSource)
+		defer src.Close()
+	}
+	detected, err := DetectObjectsTiled(app, src, &app.settings.TilingSettings, netClasses, targetClasses...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't detect objects on tiles")
+	}
+	sourceSize := image.Pt(frame.ImgSource.Cols(), frame.ImgSource.Rows())
+	scaledSize := image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows())
+	for _, detection := range detected {
+		detection.Translate(bounds.Min)
+		detection.Scale(sourceSize, scaledSize)
+	}
+	return app.filterROI(frame, detected), nil
 }
 
 // Close Free memory for underlying objects
 func (app *Application) Close() {
-	_ = app.neuralNetwork.Close()
+	_ = app.classifiers.Close()
+	if app.ownsDetector {
+		_ = app.detector.Close()
+	}
 }
@@ -0,0 +1,14 @@
+package ml
+
+import "testing"
+
+func TestNewAppWithDetectorNilLogger(t *testing.T) {
+	settings := &AppSettings{}
+	app, err := NewAppWithDetector(settings, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.logger == nil {
+		t.Fatal("logger hasn't been defaulted")
+	}
+}
@@ -0,0 +1,137 @@
+package ml
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// batchRequest Image waiting for forward pass
+type batchRequest struct {
+	img    gocv.Mat
+	params *DetectionParams
+	result chan batchResult
+}
+
+type batchResult struct {
+	detected []*DetectedObject
+	err      error
+}
+
+// BatchDetector Detector collecting images of concurrent callers (e.g. streams or tiles) into batches processed by single forward pass
+type BatchDetector struct {
+	settings *BatchSettings
+	net      *yoloNet
+	logger   *slog.Logger
+
+	requests chan *batchRequest
+	done     chan struct{}
+}
+
+// NewBatchDetector Creates BatchDetector with network from settings and starts processing of batches in separate goroutine
+func NewBatchDetector(nnSettings *NeuralNetworkSettings, settings *BatchSettings, logger *slog.Logger) (*BatchDetector, error) {
+	net, err := newYoloNet(nnSettings)
+	if err != nil {
+		return nil, err
+	}
+	bd := &BatchDetector{
+		settings: settings,
+		net:      net,
+		logger:   logger.With("component", "batch_detector"),
+		requests: make(chan *batchRequest, settings.MaxBatchSize),
+		done:     make(chan struct{}),
+	}
+	go bd.loop()
+	return bd, nil
+}
+
+// Detect implements Detector. Blocks until batch containing image is processed
+func (bd *BatchDetector) Detect(img gocv.Mat, params *DetectionParams) ([]*DetectedObject, error) {
+	req := &batchRequest{img: img, params: params, result: make(chan batchResult, 1)}
+	select {
+	case bd.requests <- req:
+	case <-bd.done:
+		return nil, errors.New("batch detector has been closed")
+	}
+	res := <-req.result
+	return res.detected, res.err
+}
+
+// Close implements Detector. Callers must not call Detect after Close
+func (bd *BatchDetector) Close() error {
+	close(bd.requests)
+	<-bd.done
+	return bd.net.net.Close()
+}
+
+func (bd *BatchDetector) loop() {
+	defer close(bd.done)
+	maxWait := time.Duration(bd.settings.MaxWaitMs * float64(time.Millisecond))
+	batch := make([]*batchRequest, 0, bd.settings.MaxBatchSize)
+	for req := range bd.requests {
+		batch = append(batch[:0], req)
+		timer := time.NewTimer(maxWait)
+	collect:
+		for len(batch) < bd.settings.MaxBatchSize {
+			select {
+			case req, ok := <-bd.requests:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		bd.process(batch)
+	}
+}
+
+// process Runs forward pass on batch and splits outputs of YOLO layers per image
+func (bd *BatchDetector) process(batch []*batchRequest) {
+	imgs := make([]gocv.Mat, len(batch))
+	for i, req := range batch {
+		imgs[i] = req.img
+	}
+	blob := gocv.NewMat()
+	defer blob.Close()
+	gocv.BlobFromImages(imgs, &blob, yoloScaleFactor, yoloSize, yoloMean, true, false, gocv.MatTypeCV32F)
+
+	start := time.Now()
+	detections := bd.net.forward(blob)
+	defer closeMats(detections)
+	bd.logger.Debug("Batch has been processed", "size", len(batch), "elapsed_ms", time.Since(start).Milliseconds())
+
+	outputs := make([][]gocv.Mat, len(detections))
+	for j := range detections {
+		outputs[j] = splitBatchOutput(detections[j], len(batch))
+	}
+	for i, req := range batch {
+		layers := make([]gocv.Mat, len(detections))
+		for j := range detections {
+			layers[j] = outputs[j][i]
+		}
+		detected, err := postprocess(layers, req.params.ConfThreshold, req.params.NmsThreshold, float32(req.img.Cols()), float32(req.img.Rows()), req.params.NetClasses, req.params.Filters)
+		closeMats(layers)
+		req.result <- batchResult{detected: detected, err: err}
+	}
+}
+
+// splitBatchOutput Splits output of YOLO layer into 2-D [boxes, 5 + classes] Mats of every image of batch. Caller has to close them.
+// Output is [batch, boxes, 5 + classes] for batches of several images and [boxes, 5 + classes] for single image
+func splitBatchOutput(output gocv.Mat, batchSize int) []gocv.Mat {
+	flat, rows := output, output.Rows()/batchSize
+	if dims := output.Size(); len(dims) == 3 {
+		flat, rows = output.Reshape(1, dims[0]*dims[1]), dims[1]
+		// Parts keep reference to data of reshaped header
+		defer flat.Close()
+	}
+	parts := make([]gocv.Mat, batchSize)
+	for i := range parts {
+		parts[i] = flat.RowRange(i*rows, (i+1)*rows)
+	}
+	return parts
+}
@@ -0,0 +1,147 @@
+package ml
+
+import (
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func float32Mat(t *testing.T, sizes []int) gocv.Mat {
+	t.Helper()
+	total := 1
+	for _, size := range sizes {
+		total *= size
+	}
+	data := make([]byte, 4*total)
+	for i := 0; i < total; i++ {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(float32(i)))
+	}
+	m, err := gocv.NewMatWithSizesFromBytes(sizes, gocv.MatTypeCV32F, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSplitBatchOutput(t *testing.T) {
+	const batch, boxes, cols = 2, 3, 4
+	for name, sizes := range map[string][]int{
+		"3-D output": {batch, boxes, cols},
+		"2-D output": {batch * boxes, cols},
+	} {
+		output := float32Mat(t, sizes)
+		parts := splitBatchOutput(output, batch)
+		_ = output.Close()
+		if len(parts) != batch {
+			t.Fatalf("%s: expected %d parts, got %d", name, batch, len(parts))
+		}
+		for i, part := range parts {
+			if part.Rows() != boxes || part.Cols() != cols {
+				t.Errorf("%s: part %d has size %dx%d, expected %dx%d", name, i, part.Rows(), part.Cols(), boxes, cols)
+				continue
+			}
+			for r := 0; r < boxes; r++ {
+				for c := 0; c < cols; c++ {
+					if v, expected := part.GetFloatAt(r, c), float32((i*boxes+r)*cols+c); v != expected {
+						t.Errorf("%s: part %d [%d, %d] = %v, expected %v", name, i, r, c, v, expected)
+					}
+				}
+			}
+		}
+		closeMats(parts)
+	}
+}
+
+// TestBatchDetectorMatchesSingle Compares batched detections with detections of every image processed separately.
+// Requires Darknet model: ML_TEST_DARKNET_CFG, ML_TEST_DARKNET_WEIGHTS, ML_TEST_DARKNET_CLASSES and comma separated ML_TEST_IMAGES
+func TestBatchDetectorMatchesSingle(t *testing.T) {
+	settings := &NeuralNetworkSettings{
+		DarknetCFG:     os.Getenv("ML_TEST_DARKNET_CFG"),
+		DarknetWeights: os.Getenv("ML_TEST_DARKNET_WEIGHTS"),
+		DarknetClasses: os.Getenv("ML_TEST_DARKNET_CLASSES"),
+	}
+	images := strings.Split(os.Getenv("ML_TEST_IMAGES"), ",")
+	if settings.DarknetCFG == "" || settings.DarknetWeights == "" || settings.DarknetClasses == "" || len(images) < 2 {
+		t.Skip("Darknet model and images are not configured")
+	}
+	classes, err := os.ReadFile(settings.DarknetClasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.NetClasses = strings.Split(string(classes), "\n")
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	params := &DetectionParams{
+		NetClasses:    settings.NetClasses,
+		Filters:       settings.NetClasses,
+		ConfThreshold: float32(settings.ConfThreshold),
+		NmsThreshold:  float32(settings.NmsThreshold),
+	}
+
+	imgs := make([]gocv.Mat, len(images))
+	for i, path := range images {
+		imgs[i] = gocv.IMRead(path, gocv.IMReadColor)
+		defer imgs[i].Close()
+		if imgs[i].Empty() {
+			t.Fatalf("can't read image %s", path)
+		}
+	}
+
+	single, err := NewNetDetector(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer single.Close()
+	expected := make([][]*DetectedObject, len(imgs))
+	for i := range imgs {
+		if expected[i], err = single.Detect(imgs[i], params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batchSettings := &BatchSettings{Enable: true, MaxBatchSize: len(imgs), MaxWaitMs: 1000}
+	batched, err := NewBatchDetector(settings, batchSettings, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer batched.Close()
+	actual := make([][]*DetectedObject, len(imgs))
+	errs := make([]error, len(imgs))
+	var wg sync.WaitGroup
+	for i := range imgs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual[i], errs[i] = batched.Detect(imgs[i], params)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range imgs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		total += len(expected[i])
+		if len(actual[i]) != len(expected[i]) {
+			t.Errorf("image %s: batched %d objects, single %d", images[i], len(actual[i]), len(expected[i]))
+			continue
+		}
+		for j := range expected[i] {
+			if actual[i][j].ClassName != expected[i][j].ClassName || IoU(actual[i][j].Rect, expected[i][j].Rect) < 0.95 {
+				t.Errorf("image %s: object %d differs: batched %v, single %v", images[i], j, actual[i][j], expected[i][j])
+			}
+		}
+	}
+	if total == 0 {
+		t.Error("no objects detected on test images")
+	}
+}
@@ -0,0 +1,157 @@
+package ml
+
+import (
+	"image"
+	"log/slog"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Attribute Result of second-stage classifier attached to detected object
+type Attribute struct {
+	Label      string  `json:"label"`
+	Confidence float32 `json:"confidence"`
+}
+
+// Classifier Second-stage model classifying crops of detected objects
+type Classifier struct {
+	settings *ClassifierSettings
+	mu       sync.Mutex
+	net      gocv.Net
+}
+
+// NewClassifier Reads model of classifier. Backend and target are shared with detector.
+// Model is run once on blank image to check that number of its outputs matches labels
+func NewClassifier(settings *ClassifierSettings, backend, target string) (*Classifier, error) {
+	net, _, err := readNet(settings.Model, settings.Config, backend, target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't create classifier '%s'", settings.Name)
+	}
+	c := &Classifier{settings: settings, net: net}
+
+	blank := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), settings.InputHeight, settings.InputWidth, gocv.MatTypeCV8UC3)
+	defer blank.Close()
+	if _, err := c.Classify(blank); err != nil {
+		_ = c.Close()
+		return nil, errors.Wrapf(err, "Can't validate classifier '%s'", settings.Name)
+	}
+	return c, nil
+}
+
+// Accepts Checks whether objects of provided class are passed to classifier
+func (c *Classifier) Accepts(className string) bool {
+	return len(c.settings.Classes) == 0 || stringInSlice(&className, c.settings.Classes)
+}
+
+// Classify returns the most probable label of image
+func (c *Classifier) Classify(img gocv.Mat) (Attribute, error) {
+	s := c.settings
+	mean := gocv.NewScalar(s.Mean[0], s.Mean[1], s.Mean[2], 0)
+	blob := gocv.BlobFromImage(img, s.Scale, image.Pt(s.InputWidth, s.InputHeight), mean, s.SwapRB, false)
+	defer blob.Close()
+
+	c.mu.Lock()
+	c.net.SetInput(blob, "")
+	output := c.net.Forward("")
+	c.mu.Unlock()
+	defer output.Close()
+
+	scores, err := output.DataPtrFloat32()
+	if err != nil {
+		return Attribute{}, errors.Wrap(err, "Can't extract scores")
+	}
+	if len(scores) != len(s.Labels) {
+		return Attribute{}, errors.Errorf("classifier '%s' returned %d scores for %d labels", s.Name, len(scores), len(s.Labels))
+	}
+	idx := 0
+	for i := range scores {
+		if scores[i] > scores[idx] {
+			idx = i
+		}
+	}
+	confidence := scores[idx]
+	if s.Softmax {
+		confidence = softmaxAt(scores, idx)
+	}
+	return Attribute{Label: s.Labels[idx], Confidence: confidence}, nil
+}
+
+// Close Frees memory of model
+func (c *Classifier) Close() error {
+	return c.net.Close()
+}
+
+// ClassifierPipeline Second-stage classifiers run on crops of detected objects
+type ClassifierPipeline struct {
+	classifiers []*Classifier
+	logger      *slog.Logger
+	throttle    *logThrottle
+}
+
+// NewClassifierPipeline Creates classifiers from prepared settings. Errors of classification are logged through provided throttle
+func NewClassifierPipeline(settings *NeuralNetworkSettings, logger *slog.Logger, throttle *logThrottle) (*ClassifierPipeline, error) {
+	cp := &ClassifierPipeline{logger: logger.With("component", "classifiers"), throttle: throttle}
+	for i := range settings.Classifiers {
+		classifier, err := NewClassifier(&settings.Classifiers[i], settings.Backend, settings.Target)
+		if err != nil {
+			_ = cp.Close()
+			return nil, err
+		}
+		cp.classifiers = append(cp.classifiers, classifier)
+	}
+	return cp, nil
+}
+
+// Apply Classifies crops of detected objects and attaches results as attributes.
+// Boxes are given in coordinates of scaled image, crops are taken from source image for better resolution
+func (cp *ClassifierPipeline) Apply(frame *FrameData, detected []*DetectedObject) {
+	if len(cp.classifiers) == 0 || len(detected) == 0 {
+		return
+	}
+	scaledSize := image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows())
+	sourceSize := image.Pt(frame.ImgSource.Cols(), frame.ImgSource.Rows())
+	bounds := image.Rectangle{Max: sourceSize}
+	for _, detection := range detected {
+		rect := scaleRect(detection.Rect, scaledSize, sourceSize).Intersect(bounds)
+		for _, classifier := range cp.classifiers {
+			if !classifier.Accepts(detection.ClassName) || rect.Dx() < classifier.settings.MinSize || rect.Dy() < classifier.settings.MinSize {
+				continue
+			}
+			crop := frame.ImgSource.Region(rect)
+			attribute, err := classifier.Classify(crop)
+			_ = crop.Close()
+			if err != nil {
+				cp.throttle.Log(cp.logger, slog.LevelWarn, "Can't classify object", "classifier", classifier.settings.Name, "error", err)
+				continue
+			}
+			if float64(attribute.Confidence) < classifier.settings.MinConfidence {
+				continue
+			}
+			if detection.Attributes == nil {
+				detection.Attributes = make(map[string]Attribute, len(cp.classifiers))
+			}
+			detection.Attributes[classifier.settings.Name] = attribute
+		}
+	}
+}
+
+// Close Frees memory of all classifiers
+func (cp *ClassifierPipeline) Close() error {
+	for _, classifier := range cp.classifiers {
+		_ = classifier.Close()
+	}
+	return nil
+}
+
+// softmaxAt returns softmax probability of the highest score with provided index
+func softmaxAt(scores []float32, idx int) float32 {
+	maxScore := scores[idx]
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(float64(score - maxScore))
+	}
+	return float32(1 / sum)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/genert/ml"
+	"github.com/genert/ml/decoder"
+)
+
+// Maximum number of decoded frames kept in memory for preprocess and detection benchmarks
+const benchMaxFrames = 32
+
+// benchLatency Latency statistics of single benchmark stage
+type benchLatency struct {
+	Samples int     `json:"samples"`
+	MeanMs  float64 `json:"mean_ms"`
+	P50Ms   float64 `json:"p50_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+	FPS     float64 `json:"fps"`
+}
+
+// benchSize Results of preprocess and detection for single input size
+type benchSize struct {
+	Width      int          `json:"width"`
+	Height     int          `json:"height"`
+	Preprocess benchLatency `json:"preprocess"`
+	Detect     benchLatency `json:"detect"`
+	Objects    float64      `json:"objects_per_frame"`
+}
+
+// benchDecode Results of H.264 decoding
+type benchDecode struct {
+	File   string  `json:"file"`
+	Frames int     `json:"frames"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	FPS    float64 `json:"fps"`
+}
+
+// benchReport Results of benchmark
+type benchReport struct {
+	Gocv      string       `json:"gocv"`
+	OpenCV    string       `json:"opencv"`
+	ModelType string       `json:"model_type"`
+	Backend   string       `json:"backend"`
+	Target    string       `json:"target"`
+	Decode    *benchDecode `json:"decode,omitempty"`
+	Sizes     []benchSize  `json:"sizes"`
+}
+
+// runBench Measures decoding, preprocessing and detection performance. Returns exit code
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	settingsFile := fs.String("settings", "config.json", "Path to application's settings with neural network to benchmark")
+	h264File := fs.String("h264", "", "Raw H.264 stream (Annex B) to measure decoding. Decoded frames are used as input for detection")
+	imageFile := fs.String("image", "", "Image used as input for detection when -h264 is not provided")
+	sizes := fs.String("sizes", "", "Comma separated list of input sizes WxH (default: reduced size of configured source)")
+	iterations := fs.Int("iterations", 100, "Number of measured iterations per input size")
+	warmup := fs.Int("warmup", 5, "Number of iterations before measuring")
+	reportFile := fs.String("report", "", "Write JSON report to file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s bench [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *h264File == "" && *imageFile == "" {
+		fmt.Fprintln(os.Stderr, "Either -h264 or -image must be provided")
+		fs.Usage()
+		return 2
+	}
+
+	settings, err := ml.NewSettings(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't read settings:", err)
+		return 1
+	}
+	inputSizes, err := parseSizes(*sizes, settings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	nn := &settings.NeuralNetworkSettings
+	report := &benchReport{
+		Gocv:      gocv.Version(),
+		OpenCV:    gocv.OpenCVVersion(),
+		ModelType: nn.ModelType,
+		Backend:   nn.Backend,
+		Target:    nn.Target,
+	}
+
+	/* Prepare input frames */
+	var frames []gocv.Mat
+	defer func() {
+		for i := range frames {
+			_ = frames[i].Close()
+		}
+	}()
+	if *h264File != "" {
+		report.Decode, frames, err = benchDecodeFile(*h264File)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		img := gocv.IMRead(*imageFile, gocv.IMReadColor)
+		if img.Empty() {
+			fmt.Fprintln(os.Stderr, "Can't read image", *imageFile)
+			return 1
+		}
+		frames = append(frames, img)
+	}
+	if len(frames) == 0 {
+		fmt.Fprintln(os.Stderr, "No frames have been decoded")
+		return 1
+	}
+
+	/* Measure preprocessing and detection */
+	app, err := ml.NewApp(settings, settings.Logger())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't create application:", err)
+		return 1
+	}
+	defer app.Close()
+
+	fd := ml.NewFrameData()
+	defer fd.Close()
+	for _, size := range inputSizes {
+		result := benchSize{Width: size.X, Height: size.Y}
+		preprocess := make([]time.Duration, 0, *iterations)
+		detect := make([]time.Duration, 0, *iterations)
+		objects := 0
+		for i := 0; i < *warmup+*iterations; i++ {
+			frames[i%len(frames)].CopyTo(&fd.ImgSource)
+			_ = fd.ImgScaledCopy.Close()
+
+			start := time.Now()
+			_ = fd.Preprocess(size.X, size.Y, nil)
+			preprocessed := time.Now()
+			detected, err := ml.DetectObjects(app, fd.ImgScaledCopy, nn.NetClasses, nn.TargetClasses...)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Can't detect objects:", err)
+				return 1
+			}
+			if i < *warmup {
+				continue
+			}
+			preprocess = append(preprocess, preprocessed.Sub(start))
+			detect = append(detect, time.Since(preprocessed))
+			objects += len(detected)
+		}
+		result.Preprocess = newBenchLatency(preprocess)
+		result.Detect = newBenchLatency(detect)
+		if *iterations > 0 {
+			result.Objects = float64(objects) / float64(*iterations)
+		}
+		report.Sizes = append(report.Sizes, result)
+	}
+
+	printBenchReport(report)
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't prepare report:", err)
+		return 1
+	}
+	if *reportFile == "" {
+		fmt.Println(string(content))
+		return 0
+	}
+	if err := os.WriteFile(*reportFile, content, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "Can't write report:", err)
+		return 1
+	}
+	return 0
+}
+
+// benchDecodeFile Decodes whole H.264 file and keeps first frames as input for detection
+func benchDecodeFile(path string) (*benchDecode, []gocv.Mat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read H.264 file: %w", err)
+	}
+	d, err := decoder.New(decoder.PixelFormatBGR, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create H.264 decoder: %w", err)
+	}
+	defer d.Close()
+
+	result := &benchDecode{File: path}
+	var frames []gocv.Mat
+	var elapsed time.Duration
+	for len(data) > 0 {
+		start := time.Now()
+		frame, n, err := d.DecodeStream(data)
+		elapsed += time.Since(start)
+		if err != nil {
+			return nil, frames, fmt.Errorf("can't decode H.264 stream: %w", err)
+		}
+		if n <= 0 && frame == nil {
+			break
+		}
+		data = data[n:]
+		if frame == nil {
+			continue
+		}
+		result.Frames++
+		result.Width, result.Height = frame.Width, frame.Height
+		if len(frames) < benchMaxFrames {
+			m, err := gocv.NewMatFromBytes(frame.Height, frame.Width, gocv.MatTypeCV8UC3, frame.Data)
+			if err != nil {
+				return nil, frames, err
+			}
+			// Keep own copy as Mat references decoder's buffer
+			frames = append(frames, m.Clone())
+			_ = m.Close()
+		}
+	}
+	if elapsed > 0 {
+		result.FPS = float64(result.Frames) / elapsed.Seconds()
+	}
+	return result, frames, nil
+}
+
+// parseSizes Parses list of WxH sizes. Reduced size of configured source is used by default
+func parseSizes(value string, settings *ml.AppSettings) ([]image.Point, error) {
+	if value == "" {
+		return []image.Point{settings.ReducedSize()}, nil
+	}
+	var sizes []image.Point
+	for _, item := range strings.Split(value, ",") {
+		w, h, ok := strings.Cut(strings.TrimSpace(item), "x")
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("invalid size '%s', expected WxH", item)
+		}
+		sizes = append(sizes, image.Pt(width, height))
+	}
+	return sizes, nil
+}
+
+// newBenchLatency Calculates latency percentiles (nearest rank)
+func newBenchLatency(durations []time.Duration) benchLatency {
+	if len(durations) == 0 {
+		return benchLatency{}
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	percentile := func(p float64) float64 {
+		idx := int(math.Ceil(p*float64(len(durations)))) - 1
+		return toMs(durations[max(idx, 0)])
+	}
+	mean := total / time.Duration(len(durations))
+	latency := benchLatency{
+		Samples: len(durations),
+		MeanMs:  toMs(mean),
+		P50Ms:   percentile(0.50),
+		P95Ms:   percentile(0.95),
+		P99Ms:   percentile(0.99),
+		MaxMs:   toMs(durations[len(durations)-1]),
+	}
+	if mean > 0 {
+		latency.FPS = float64(time.Second) / float64(mean)
+	}
+	return latency
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// printBenchReport Prints human readable table of benchmark results
+func printBenchReport(report *benchReport) {
+	fmt.Printf("OpenCV %s, gocv %s, model %s, backend %s, target %s\n", report.OpenCV, report.Gocv, report.ModelType, report.Backend, report.Target)
+	if report.Decode != nil {
+		fmt.Printf("Decode %s: %d frames %dx%d, %.1f FPS\n", report.Decode.File, report.Decode.Frames, report.Decode.Width, report.Decode.Height, report.Decode.FPS)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "size\tstage\tmean ms\tp50 ms\tp95 ms\tp99 ms\tmax ms\tFPS\t")
+	for _, size := range report.Sizes {
+		for _, stage := range []struct {
+			name    string
+			latency benchLatency
+		}{{"preprocess", size.Preprocess}, {"detect", size.Detect}} {
+			l := stage.latency
+			fmt.Fprintf(tw, "%dx%d\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.1f\t\n", size.Width, size.Height, stage.name, l.MeanMs, l.P50Ms, l.P95Ms, l.P99Ms, l.MaxMs, l.FPS)
+		}
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/genert/ml"
+)
+
+// detectResult Detections of single image
+type detectResult struct {
+	Image      string               `json:"image"`
+	Width      int                  `json:"width"`
+	Height     int                  `json:"height"`
+	Detections []*ml.DetectedObject `json:"detections"`
+}
+
+// runDetect Detects objects on single image or images of directory. Returns exit code
+func runDetect(args []string) int {
+	fs := flag.NewFlagSet("detect", flag.ExitOnError)
+	settingsFile := fs.String("settings", "config.json", "Path to application's settings")
+	input := fs.String("input", "", "Image file or directory with images")
+	outputDir := fs.String("output", "", "Directory for annotated images (not written when empty)")
+	jsonFile := fs.String("json", "", "Write detections to JSON file ('-' for stdout)")
+	csvFile := fs.String("csv", "", "Write detections to CSV file ('-' for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s detect [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "Flag -input is required")
+		fs.Usage()
+		return 2
+	}
+	images := []string{*input}
+	if info, err := os.Stat(*input); err != nil {
+		fmt.Fprintln(os.Stderr, "Can't read input:", err)
+		return 1
+	} else if info.IsDir() {
+		if images, err = ml.ListImages(*input); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if *outputDir != "" {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't create output directory:", err)
+			return 1
+		}
+		// Annotated images keep names of input images, so they must not be written next to them
+		if inputDir, ok := sameDirectory(*outputDir, images); ok {
+			fmt.Fprintf(os.Stderr, "Output directory %s is the directory of input images %s. Annotated images would overwrite originals\n", *outputDir, inputDir)
+			return 2
+		}
+	}
+
+	settings, err := ml.NewSettings(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't read settings:", err)
+		return 1
+	}
+	logger := settings.Logger()
+	app, err := ml.NewApp(settings, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't create application:", err)
+		return 1
+	}
+	defer app.Close()
+	overlay := ml.NewOverlayRenderer(&settings.OverlaySettings, settings.StreamName)
+
+	results := make([]detectResult, 0, len(images))
+	failed := 0
+	for _, path := range images {
+		img := gocv.IMRead(path, gocv.IMReadColor)
+		if img.Empty() {
+			_ = img.Close()
+			logger.Error("Can't read image", "image", path)
+			failed++
+			continue
+		}
+		detected, err := app.DetectImage(img, image.Point{})
+		if err != nil {
+			_ = img.Close()
+			logger.Error("Can't detect objects", "image", path, "error", err)
+			failed++
+			continue
+		}
+		results = append(results, detectResult{Image: path, Width: img.Cols(), Height: img.Rows(), Detections: detected})
+		logger.Info("Image has been processed", "image", path, "objects", len(detected))
+
+		if *outputDir != "" {
+			timestamp := time.Now()
+			if info, err := os.Stat(path); err == nil {
+				timestamp = info.ModTime()
+			}
+			overlay.Draw(&img, detected, timestamp)
+			output := filepath.Join(*outputDir, filepath.Base(path))
+			if !gocv.IMWrite(output, img) {
+				logger.Error("Can't write annotated image", "image", output)
+				failed++
+			}
+		}
+		_ = img.Close()
+	}
+
+	if *jsonFile != "" {
+		if err := writeOutput(*jsonFile, func(f *os.File) error {
+			encoder := json.NewEncoder(f)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(results)
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't write JSON:", err)
+			return 1
+		}
+	}
+	if *csvFile != "" {
+		if err := writeOutput(*csvFile, func(f *os.File) error {
+			return writeDetectionsCSV(f, results)
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't write CSV:", err)
+			return 1
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// sameDirectory Checks whether directory contains any of provided files and returns directory of the first such file
+func sameDirectory(dir string, files []string) (string, bool) {
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, file := range files {
+		if info, err := os.Stat(filepath.Dir(file)); err == nil && os.SameFile(dirInfo, info) {
+			return filepath.Dir(file), true
+		}
+	}
+	return "", false
+}
+
+// writeDetectionsCSV Writes one row per detection
+func writeDetectionsCSV(f *os.File, results []detectResult) error {
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"image", "class_id", "class_name", "confidence", "x", "y", "width", "height"}); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, d := range result.Detections {
+			if err := w.Write([]string{
+				result.Image,
+				strconv.Itoa(d.ClassID),
+				d.ClassName,
+				strconv.FormatFloat(float64(d.Confidence), 'f', 4, 32),
+				strconv.Itoa(d.Rect.Min.X),
+				strconv.Itoa(d.Rect.Min.Y),
+				strconv.Itoa(d.Rect.Dx()),
+				strconv.Itoa(d.Rect.Dy()),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeOutput Writes to file or to stdout when path is '-'
+func writeOutput(path string, write func(f *os.File) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
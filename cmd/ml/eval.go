@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gocv.io/x/gocv"
+
+	"github.com/genert/ml"
+)
+
+// runEval Evaluates detector on labeled dataset and writes report. Returns exit code
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	settingsFile := fs.String("settings", "", "Path to application's settings to evaluate. Images are scaled to reduced size of configured source")
+	imagesDir := fs.String("images", "", "Directory with images of dataset")
+	labelsDir := fs.String("labels", "", "Directory with YOLO txt labels (default: 'labels' next to images directory)")
+	cocoFile := fs.String("coco", "", "COCO JSON annotations. Used instead of YOLO labels when provided")
+	classesFile := fs.String("classes", "", "File with class names (one per line) for YOLO class IDs (default: classes of neural network)")
+	predictionsDir := fs.String("predictions", "", "Directory with precomputed detections in YOLO txt format with confidence. Neural network is not used when provided")
+	confThreshold := fs.Float64("conf", 0, "Confidence threshold override (default: from settings)")
+	reportFile := fs.String("report", "", "Write JSON report to file")
+	minMAP := fs.Float64("min-map50", 0, "Exit with non-zero code when mAP@0.5 is below this value")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s eval [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *imagesDir == "" {
+		fmt.Fprintln(os.Stderr, "Flag -images is required")
+		fs.Usage()
+		return 2
+	}
+	if *settingsFile == "" && *predictionsDir == "" {
+		fmt.Fprintln(os.Stderr, "Either -settings or -predictions must be provided")
+		fs.Usage()
+		return 2
+	}
+
+	var settings *ml.AppSettings
+	if *settingsFile != "" {
+		var err error
+		if settings, err = ml.NewSettings(*settingsFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't read settings:", err)
+			return 1
+		}
+		if *confThreshold > 0 {
+			settings.NeuralNetworkSettings.ConfThreshold = *confThreshold
+		}
+	}
+
+	var classes []string
+	if *classesFile != "" {
+		var err error
+		if classes, err = readClasses(*classesFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else if settings != nil {
+		classes = settings.NeuralNetworkSettings.NetClasses
+	}
+
+	/* Load dataset */
+	var samples []*ml.EvalSample
+	var err error
+	if *cocoFile != "" {
+		samples, err = ml.LoadCOCODataset(*imagesDir, *cocoFile)
+	} else {
+		if len(classes) == 0 {
+			fmt.Fprintln(os.Stderr, "Class names are required for YOLO labels: provide -classes or -settings")
+			return 2
+		}
+		if *labelsDir == "" {
+			*labelsDir = filepath.Join(filepath.Dir(filepath.Clean(*imagesDir)), "labels")
+		}
+		samples, err = ml.LoadYOLODataset(*imagesDir, *labelsDir, classes)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't load dataset:", err)
+		return 1
+	}
+
+	/* Prepare detector: fake one reading predictions from files or neural network */
+	var detect ml.EvalDetectFunc
+	if *predictionsDir != "" {
+		if len(classes) == 0 {
+			fmt.Fprintln(os.Stderr, "Class names are required for predictions: provide -classes or -settings")
+			return 2
+		}
+		detect = func(sample *ml.EvalSample, img gocv.Mat) ([]*ml.DetectedObject, error) {
+			path := filepath.Join(*predictionsDir, strings.TrimSuffix(filepath.Base(sample.ImagePath), filepath.Ext(sample.ImagePath))+".txt")
+			return ml.LoadYOLOPredictions(path, classes, image.Pt(img.Cols(), img.Rows()))
+		}
+	} else {
+		// Same pipeline as for stream: scaling to reduced size, region of interest, tiling and classifiers
+		app, err := ml.NewApp(settings, settings.Logger())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Can't create application:", err)
+			return 1
+		}
+		defer app.Close()
+		reduced := settings.ReducedSize()
+		detect = func(_ *ml.EvalSample, img gocv.Mat) ([]*ml.DetectedObject, error) {
+			return app.DetectImage(img, reduced)
+		}
+	}
+
+	report, err := ml.Evaluate(samples, detect)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't evaluate:", err)
+		return 1
+	}
+	_ = report.WriteText(os.Stdout)
+
+	if *reportFile != "" {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Can't prepare report:", err)
+			return 1
+		}
+		if err := os.WriteFile(*reportFile, content, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't write report:", err)
+			return 1
+		}
+	}
+	if report.MAP50 < *minMAP {
+		fmt.Fprintf(os.Stderr, "mAP@0.5 %.4f is below %.4f\n", report.MAP50, *minMAP)
+		return 1
+	}
+	return 0
+}
+
+// readClasses Reads class names, one per line
+func readClasses(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read classes file: %w", err)
+	}
+	return strings.Split(strings.TrimRight(string(content), "\n"), "\n"), nil
+}
@@ -1,45 +1,179 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
-	"log"
-	_ "net/http/pprof"
-	"time"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
 
 	"gocv.io/x/gocv"
 
 	"github.com/genert/ml"
 )
 
+// settingsFiles List of settings files provided by repeated flag
+type settingsFiles []string
+
+func (sf *settingsFiles) String() string {
+	return strings.Join(*sf, ",")
+}
+
+func (sf *settingsFiles) Set(value string) error {
+	*sf = append(*sf, value)
+	return nil
+}
+
+// init Locks main goroutine to main OS thread: imshow() windows must be handled by it on some platforms (e.g. darwin)
+func init() {
+	runtime.LockOSThread()
+}
+
 func main() {
-	settingsFile := flag.String("settings", "config.json", "Path to application's settings")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "eval":
+			os.Exit(runEval(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "detect":
+			os.Exit(runDetect(os.Args[2:]))
+		}
+	}
+	runStreams()
+}
 
-	fmt.Printf("gocv version: %s\n", gocv.Version())
-	fmt.Printf("opencv lib version: %s\n", gocv.OpenCVVersion())
+// runStreams Processes video streams of provided settings files until interrupted
+func runStreams() {
+	var files settingsFiles
+	flag.Var(&files, "settings", "Path to application's settings. Repeat for multiple streams (default config.json)")
+	flag.Parse()
+	if len(files) == 0 {
+		files = settingsFiles{"config.json"}
+	}
 
 	/* Read settings */
-	settings, err := ml.NewSettings(*settingsFile)
-	if err != nil {
-		log.Println(err)
-		return
+	streams := make([]*ml.AppSettings, 0, len(files))
+	for _, file := range files {
+		settings, err := ml.NewSettings(file)
+		if err != nil {
+			slog.Error("Can't read settings", "file", file, "error", err)
+			return
+		}
+		streams = append(streams, settings)
 	}
 
-	app, err := ml.NewApp(settings)
-	if err != nil {
-		log.Println(err)
-		return
+	logger := streams[0].Logger()
+	slog.SetDefault(logger)
+	logger.Info("Versions", "gocv", gocv.Version(), "opencv", gocv.OpenCVVersion())
+
+	/* Database of event store is locked by the first stream opening it */
+	stores := make(map[string]string, len(streams))
+	for _, settings := range streams {
+		if !settings.EventStoreSettings.Enable {
+			continue
+		}
+		path := filepath.Clean(settings.EventStoreSettings.Path)
+		if other, ok := stores[path]; ok {
+			logger.Error("Event store can't be shared by streams. Provide different 'path' in 'event_store_settings'", "path", path, "streams", []string{other, settings.StreamName})
+			return
+		}
+		stores[path] = settings.StreamName
+	}
+
+	/* Only the last stream runs on main thread, so imshow() is allowed for it only */
+	for _, settings := range streams[:len(streams)-1] {
+		if settings.MjpegSettings.ImshowEnable {
+			logger.Warn("imshow() is supported for the last stream only. It has been disabled", "stream", settings.StreamName)
+			settings.MjpegSettings.ImshowEnable = false
+		}
 	}
-	defer app.Close()
 
-	if err := app.Run(); err != nil {
-		log.Println(err)
+	/* Share batching detector between streams with the same neural network if needed */
+	detectors := make([]ml.Detector, len(streams))
+	for i, settings := range streams {
+		if detectors[i] != nil {
+			continue
+		}
+		group := []int{i}
+		for j := i + 1; j < len(streams); j++ {
+			if streams[j].NeuralNetworkSettings.SameNetwork(&settings.NeuralNetworkSettings) {
+				group = append(group, j)
+			}
+		}
+		if !settings.BatchSettings.Enable && len(group) == 1 {
+			continue
+		}
+		if len(group) != len(streams) {
+			logger.Info("Streams have different neural networks. Detector is shared between streams of the same network", "stream", settings.StreamName, "streams", len(group))
+		}
+		detector, err := newSharedDetector(settings, logger)
+		if err != nil {
+			logger.Error("Can't create detector", "stream", settings.StreamName, "error", err)
+			return
+		}
+		defer detector.Close()
+		for _, j := range group {
+			detectors[j] = detector
+		}
 	}
 
-	fmt.Println("Shutting down...")
-	time.Sleep(2 * time.Second) // @todo temporary fix: need to wait a bit time for last call of neuralNet.Detect(...)
+	apps := make([]*ml.Application, 0, len(streams))
+	for i, settings := range streams {
+		var app *ml.Application
+		var err error
+		if detectors[i] != nil {
+			app, err = ml.NewAppWithDetector(settings, detectors[i], settings.Logger())
+		} else {
+			app, err = ml.NewApp(settings, settings.Logger())
+		}
+		if err != nil {
+			logger.Error("Can't create application", "stream", settings.StreamName, "error", err)
+			return
+		}
+		defer app.Close()
+		apps = append(apps, app)
+	}
+
+	/* Stop processing on SIGINT/SIGTERM (or 'ESC' in imshow() window) */
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Hard release memory
-	app.Close()
+	run := func(app *ml.Application, stream string) {
+		if err := app.Run(ctx); err != nil {
+			logger.Error("Application has been stopped", "stream", stream, "error", err)
+		}
+	}
+	var wg sync.WaitGroup
+	last := len(apps) - 1
+	for i, app := range apps[:last] {
+		wg.Add(1)
+		go func(app *ml.Application, stream string) {
+			defer wg.Done()
+			run(app, stream)
+		}(app, streams[i].StreamName)
+	}
+	// The last stream is processed on main goroutine, which is locked to main thread
+	run(apps[last], streams[last].StreamName)
+	wg.Wait()
+	logger.Info("Shutting down...")
+}
+
+// newSharedDetector Creates detector shared between streams. Batch settings of provided stream are used
+func newSharedDetector(settings *ml.AppSettings, logger *slog.Logger) (ml.Detector, error) {
+	nn := &settings.NeuralNetworkSettings
+	if nn.ModelType != "darknet" {
+		logger.Warn("Batching is supported by Darknet models only. Detector is shared without batching", "model_type", nn.ModelType)
+		return ml.NewDetector(nn)
+	}
+	if batch := &settings.BatchSettings; !batch.Enable {
+		batch.Enable = true
+		batch.Prepare()
+	}
+	return ml.NewBatchDetector(nn, &settings.BatchSettings, logger)
 }
@@ -0,0 +1,45 @@
+package ml
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+//go:embed web/dashboard
+var dashboardFiles embed.FS
+
+// RegisterDashboard Registers dashboard page, pipeline statistics, detection feed and runtime configuration endpoints
+func (app *Application) RegisterDashboard(stats *PipelineStats) {
+	files, _ := fs.Sub(dashboardFiles, "web/dashboard")
+	app.router.Handle("/dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+	app.router.PathPrefix("/dashboard/").Handler(http.StripPrefix("/dashboard/", http.FileServer(http.FS(files)))).Methods(http.MethodGet)
+
+	app.router.HandleFunc("/api/stats", func(w http.ResponseWriter, _ *http.Request) {
+		snapshot := stats.Snapshot()
+		snapshot.Stream = app.settings.StreamName
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(snapshot)
+	}).Methods(http.MethodGet)
+
+	app.router.HandleFunc("/api/detections", func(w http.ResponseWriter, _ *http.Request) {
+		detected, timestamp := stats.Detections()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Stream    string            `json:"stream"`
+			Timestamp time.Time         `json:"timestamp"`
+			Objects   []*DetectedObject `json:"objects"`
+		}{app.settings.StreamName, timestamp, detected})
+	}).Methods(http.MethodGet)
+
+	app.router.HandleFunc("/api/config", app.serveConfig).Methods(http.MethodGet)
+	// Changing configuration is never exposed without authentication: the API is reachable from any host and via CORS
+	if !app.settings.HTTPSettings.Auth.Enable {
+		app.logger.Warn("Runtime configuration changes are disabled because HTTP authentication is not enabled")
+		return
+	}
+	update := RequireRole(app.settings.DashboardSettings.AdminRole, http.HandlerFunc(app.serveConfigUpdate))
+	app.router.Handle("/api/config", update).Methods(http.MethodPatch, http.MethodPut)
+}
@@ -0,0 +1,513 @@
+package ml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"log/slog"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Directory of COCO annotations files (one per day) in export directory
+const datasetCOCODir = "coco"
+
+// datasetObject Pre-label of object in coordinates of exported image
+type datasetObject struct {
+	classID    int
+	className  string
+	confidence float32
+	rect       image.Rectangle
+	polygon    []image.Point
+}
+
+// datasetSample Exported frame waiting to be written
+type datasetSample struct {
+	name      string
+	reason    string
+	timestamp time.Time
+	size      image.Point
+	jpeg      []byte
+	objects   []datasetObject
+}
+
+// flickerState Appearance history of track
+type flickerState struct {
+	lastFrame int64
+	lastSeen  time.Time
+	gaps      []time.Time
+}
+
+// DatasetExporter Saves frames with uncertain detections or flickering tracks together with pre-labels for further annotation and training
+type DatasetExporter struct {
+	settings   *DatasetExportSettings
+	streamName string
+	netClasses []string
+	logger     *slog.Logger
+
+	frame    int64
+	tracks   map[int]*flickerState
+	lastSave time.Time
+	saves    []time.Time
+	hashes   []uint64
+
+	queue chan *datasetSample
+	done  chan struct{}
+
+	// COCO annotations of current day. Written when queue is drained
+	coco      *cocoDataset
+	cocoDay   string
+	cocoDirty bool
+}
+
+// NewDatasetExporter Creates DatasetExporter. Directories for selected formats are created
+func NewDatasetExporter(settings *DatasetExportSettings, streamName string, netClasses []string, logger *slog.Logger) (*DatasetExporter, error) {
+	dirs := []string{"images"}
+	if settings.HasFormat("yolo") {
+		dirs = append(dirs, "labels")
+	}
+	if settings.HasFormat("voc") {
+		dirs = append(dirs, "annotations")
+	}
+	if settings.HasFormat("coco") {
+		dirs = append(dirs, datasetCOCODir)
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(settings.Directory, dir), 0o755); err != nil {
+			return nil, errors.Wrapf(err, "Can't create dataset directory %s", dir)
+		}
+	}
+	de := &DatasetExporter{
+		settings:   settings,
+		streamName: streamName,
+		netClasses: netClasses,
+		logger:     logger.With("component", "dataset"),
+		tracks:     make(map[int]*flickerState),
+		queue:      make(chan *datasetSample, settings.QueueSize),
+		done:       make(chan struct{}),
+	}
+	if settings.HasFormat("yolo") {
+		classes := strings.Join(netClasses, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(settings.Directory, "classes.txt"), []byte(classes), 0o644); err != nil {
+			return nil, errors.Wrap(err, "Can't write YOLO classes")
+		}
+	}
+	go de.writeLoop()
+	return de, nil
+}
+
+// Consume implements FrameSink. Only frames with fresh inference results are considered: boxes predicted by tracker or reused from static scene are not pre-labels.
+// Export can't be enabled together with redaction (see NewSettings), so source image is never redacted here
+func (de *DatasetExporter) Consume(frame *FrameData, detected []*DetectedObject) error {
+	if !frame.Inferred {
+		return nil
+	}
+	de.frame++
+	reason := de.trigger(frame.Timestamp, detected)
+	if reason == "" || !de.allowed(frame.Timestamp) {
+		return nil
+	}
+
+	hash := perceptualHash(frame.ImgSource)
+	if de.duplicate(hash) {
+		de.logger.Debug("Frame is similar to recently exported one. Skipping", "reason", reason)
+		return nil
+	}
+
+	data, err := EncodeImage(gocv.JPEGFileExt, frame.ImgSource, gocv.IMWriteJpegQuality, de.settings.JPEGQuality)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode frame for dataset")
+	}
+	sourceSize := image.Pt(frame.ImgSource.Cols(), frame.ImgSource.Rows())
+	scaledSize := image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows())
+	bounds := image.Rectangle{Max: sourceSize}
+	sample := &datasetSample{
+		name:      fmt.Sprintf("%s_%s", sanitizeFileName(de.streamName), frame.Timestamp.Format("20060102T150405.000")),
+		reason:    reason,
+		timestamp: frame.Timestamp,
+		size:      sourceSize,
+		jpeg:      data,
+		objects:   make([]datasetObject, 0, len(detected)),
+	}
+	for _, detection := range detected {
+		object := datasetObject{
+			classID:    detection.ClassID,
+			className:  detection.ClassName,
+			confidence: detection.Confidence,
+			rect:       scaleRect(detection.Rect, scaledSize, sourceSize).Intersect(bounds),
+		}
+		if object.rect.Empty() {
+			continue
+		}
+		if detection.Mask != nil {
+			for _, p := range detection.Mask.Polygon {
+				object.polygon = append(object.polygon, scaleRect(image.Rectangle{Min: p, Max: p}, scaledSize, sourceSize).Min)
+			}
+		}
+		sample.objects = append(sample.objects, object)
+	}
+
+	select {
+	case de.queue <- sample:
+		de.remember(frame.Timestamp, hash)
+	default:
+		de.logger.Warn("Dataset export queue is full. Frame has been dropped", "reason", reason)
+	}
+	return nil
+}
+
+// Close implements FrameSink. Waits until queued frames are written
+func (de *DatasetExporter) Close() error {
+	close(de.queue)
+	<-de.done
+	return nil
+}
+
+// trigger returns reason to export frame or empty string
+func (de *DatasetExporter) trigger(ts time.Time, detected []*DetectedObject) string {
+	window := time.Duration(de.settings.FlickerWindowSec * float64(time.Second))
+	reason := ""
+	for _, detection := range detected {
+		if len(de.settings.Classes) != 0 && !stringInSlice(&detection.ClassName, de.settings.Classes) {
+			continue
+		}
+		confidence := float64(detection.Confidence)
+		if reason == "" && confidence >= de.settings.MinConfidence && confidence <= de.settings.MaxConfidence {
+			reason = "uncertain"
+		}
+		if de.settings.FlickerMinGaps == 0 || detection.TrackID == 0 {
+			continue
+		}
+		state, ok := de.tracks[detection.TrackID]
+		if !ok {
+			state = &flickerState{}
+			de.tracks[detection.TrackID] = state
+		} else if state.lastFrame < de.frame-1 {
+			// Track has been missing on previous frames
+			state.gaps = append(state.gaps, ts)
+		}
+		state.lastFrame = de.frame
+		state.lastSeen = ts
+		for len(state.gaps) > 0 && ts.Sub(state.gaps[0]) > window {
+			state.gaps = state.gaps[1:]
+		}
+		if len(state.gaps) >= de.settings.FlickerMinGaps {
+			state.gaps = state.gaps[:0]
+			if reason == "" {
+				reason = "flicker"
+			}
+		}
+	}
+	for id, state := range de.tracks {
+		if ts.Sub(state.lastSeen) > window {
+			delete(de.tracks, id)
+		}
+	}
+	return reason
+}
+
+// allowed Checks rate limits
+func (de *DatasetExporter) allowed(ts time.Time) bool {
+	if !de.lastSave.IsZero() && ts.Sub(de.lastSave).Seconds() < de.settings.MinIntervalSec {
+		return false
+	}
+	for len(de.saves) > 0 && ts.Sub(de.saves[0]) > time.Hour {
+		de.saves = de.saves[1:]
+	}
+	return len(de.saves) < de.settings.MaxPerHour
+}
+
+// duplicate Checks whether frame with similar hash has been exported recently
+func (de *DatasetExporter) duplicate(hash uint64) bool {
+	if de.settings.HashDistance < 0 {
+		return false
+	}
+	for _, h := range de.hashes {
+		if bits.OnesCount64(h^hash) <= de.settings.HashDistance {
+			return true
+		}
+	}
+	return false
+}
+
+func (de *DatasetExporter) remember(ts time.Time, hash uint64) {
+	de.lastSave = ts
+	de.saves = append(de.saves, ts)
+	de.hashes = append(de.hashes, hash)
+	if len(de.hashes) > de.settings.HashHistory {
+		de.hashes = de.hashes[len(de.hashes)-de.settings.HashHistory:]
+	}
+}
+
+func (de *DatasetExporter) writeLoop() {
+	defer close(de.done)
+	for sample := range de.queue {
+		if err := de.write(sample); err != nil {
+			de.logger.Error("Can't write dataset sample", "name", sample.name, "error", err)
+		} else {
+			de.logger.Info("Frame has been exported to dataset", "name", sample.name, "reason", sample.reason, "objects", len(sample.objects))
+		}
+		// Write accumulated COCO annotations once burst of samples is handled
+		if len(de.queue) == 0 {
+			if err := de.flushCOCO(); err != nil {
+				de.logger.Error("Can't write COCO annotations", "error", err)
+			}
+		}
+	}
+	if err := de.flushCOCO(); err != nil {
+		de.logger.Error("Can't write COCO annotations", "error", err)
+	}
+}
+
+// write Writes image and pre-labels of sample in selected formats
+func (de *DatasetExporter) write(sample *datasetSample) error {
+	imageName := sample.name + ".jpg"
+	if err := os.WriteFile(filepath.Join(de.settings.Directory, "images", imageName), sample.jpeg, 0o644); err != nil {
+		return err
+	}
+	for _, format := range de.settings.Formats {
+		var err error
+		switch format {
+		case "yolo":
+			err = writeYOLOLabels(filepath.Join(de.settings.Directory, "labels", sample.name+".txt"), sample)
+		case "voc":
+			err = writeVOCAnnotation(filepath.Join(de.settings.Directory, "annotations", sample.name+".xml"), imageName, sample)
+		case "coco":
+			err = de.addCOCO(imageName, sample)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Can't write %s pre-labels", format)
+		}
+	}
+	return nil
+}
+
+// addCOCO Adds sample to COCO annotations of its day. Annotations of previous day are written and existing annotations of the day are loaded to be appended
+func (de *DatasetExporter) addCOCO(imageName string, sample *datasetSample) error {
+	day := sample.timestamp.Format("2006-01-02")
+	if day != de.cocoDay {
+		if err := de.flushCOCO(); err != nil {
+			return err
+		}
+		coco, err := loadCOCODataset(de.cocoPath(day), de.netClasses)
+		if err != nil {
+			return err
+		}
+		de.coco, de.cocoDay = coco, day
+	}
+	de.coco.add(imageName, sample)
+	de.cocoDirty = true
+	return nil
+}
+
+// flushCOCO Writes COCO annotations of current day if they have been changed
+func (de *DatasetExporter) flushCOCO() error {
+	if !de.cocoDirty {
+		return nil
+	}
+	if err := de.coco.save(de.cocoPath(de.cocoDay)); err != nil {
+		return err
+	}
+	de.cocoDirty = false
+	return nil
+}
+
+func (de *DatasetExporter) cocoPath(day string) string {
+	return filepath.Join(de.settings.Directory, datasetCOCODir, "annotations_"+day+".json")
+}
+
+// writeYOLOLabels Writes 'class cx cy w h' lines normalized by image size
+func writeYOLOLabels(path string, sample *datasetSample) error {
+	var sb strings.Builder
+	w, h := float64(sample.size.X), float64(sample.size.Y)
+	for _, object := range sample.objects {
+		r := object.rect
+		fmt.Fprintf(&sb, "%d %.6f %.6f %.6f %.6f\n", object.classID,
+			(float64(r.Min.X)+float64(r.Dx())/2)/w, (float64(r.Min.Y)+float64(r.Dy())/2)/h,
+			float64(r.Dx())/w, float64(r.Dy())/h)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
+
+// vocAnnotation Pascal VOC annotation
+type vocAnnotation struct {
+	XMLName  xml.Name    `xml:"annotation"`
+	Folder   string      `xml:"folder"`
+	Filename string      `xml:"filename"`
+	Size     vocSize     `xml:"size"`
+	Objects  []vocObject `xml:"object"`
+}
+
+type vocSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+type vocObject struct {
+	Name      string `xml:"name"`
+	Pose      string `xml:"pose"`
+	Truncated int    `xml:"truncated"`
+	Difficult int    `xml:"difficult"`
+	BndBox    struct {
+		XMin int `xml:"xmin"`
+		YMin int `xml:"ymin"`
+		XMax int `xml:"xmax"`
+		YMax int `xml:"ymax"`
+	} `xml:"bndbox"`
+}
+
+func writeVOCAnnotation(path, imageName string, sample *datasetSample) error {
+	annotation := vocAnnotation{
+		Folder:   "images",
+		Filename: imageName,
+		Size:     vocSize{Width: sample.size.X, Height: sample.size.Y, Depth: 3},
+	}
+	for _, object := range sample.objects {
+		o := vocObject{Name: object.className, Pose: "Unspecified"}
+		// VOC coordinates are 1-based and inclusive
+		o.BndBox.XMin, o.BndBox.YMin = object.rect.Min.X+1, object.rect.Min.Y+1
+		o.BndBox.XMax, o.BndBox.YMax = object.rect.Max.X, object.rect.Max.Y
+		annotation.Objects = append(annotation.Objects, o)
+	}
+	data, err := xml.MarshalIndent(annotation, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), data...), 0o644)
+}
+
+// cocoDataset COCO annotations accumulated across exported frames
+type cocoDataset struct {
+	Images      []cocoImage      `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []cocoCategory   `json:"categories"`
+}
+
+type cocoImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	// Time of frame and reason of export. Not part of COCO format, ignored by tools
+	DateCaptured string `json:"date_captured"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+type cocoAnnotation struct {
+	ID           int         `json:"id"`
+	ImageID      int         `json:"image_id"`
+	CategoryID   int         `json:"category_id"`
+	BBox         [4]int      `json:"bbox"`
+	Area         int         `json:"area"`
+	Segmentation [][]float64 `json:"segmentation"`
+	IsCrowd      int         `json:"iscrowd"`
+	Score        float32     `json:"score"`
+}
+
+type cocoCategory struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// loadCOCODataset Reads existing annotations or prepares empty dataset with categories of network
+func loadCOCODataset(path string, netClasses []string) (*cocoDataset, error) {
+	coco := &cocoDataset{}
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(content, coco); err != nil {
+			return nil, errors.Wrapf(err, "Can't parse existing COCO annotations %s", path)
+		}
+	case !os.IsNotExist(err):
+		return nil, errors.Wrapf(err, "Can't read existing COCO annotations %s", path)
+	}
+	if len(coco.Categories) == 0 {
+		for i, name := range netClasses {
+			if name != "" {
+				coco.Categories = append(coco.Categories, cocoCategory{ID: i, Name: name})
+			}
+		}
+	}
+	return coco, nil
+}
+
+func (coco *cocoDataset) add(imageName string, sample *datasetSample) {
+	imageID := 1
+	if n := len(coco.Images); n > 0 {
+		imageID = coco.Images[n-1].ID + 1
+	}
+	coco.Images = append(coco.Images, cocoImage{
+		ID:           imageID,
+		FileName:     imageName,
+		Width:        sample.size.X,
+		Height:       sample.size.Y,
+		DateCaptured: sample.timestamp.Format(time.RFC3339),
+		Reason:       sample.reason,
+	})
+	annotationID := 1
+	if n := len(coco.Annotations); n > 0 {
+		annotationID = coco.Annotations[n-1].ID + 1
+	}
+	for _, object := range sample.objects {
+		r := object.rect
+		annotation := cocoAnnotation{
+			ID:           annotationID,
+			ImageID:      imageID,
+			CategoryID:   object.classID,
+			BBox:         [4]int{r.Min.X, r.Min.Y, r.Dx(), r.Dy()},
+			Area:         r.Dx() * r.Dy(),
+			Segmentation: [][]float64{},
+			Score:        object.confidence,
+		}
+		if len(object.polygon) >= 3 {
+			polygon := make([]float64, 0, 2*len(object.polygon))
+			for _, p := range object.polygon {
+				polygon = append(polygon, float64(p.X), float64(p.Y))
+			}
+			annotation.Segmentation = append(annotation.Segmentation, polygon)
+		}
+		coco.Annotations = append(coco.Annotations, annotation)
+		annotationID++
+	}
+}
+
+// save Rewrites annotations file atomically
+func (coco *cocoDataset) save(path string) error {
+	data, err := json.Marshal(coco)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// perceptualHash Difference hash (dHash) of image: 64 bits comparing brightness of neighbour pixels of 9x8 grayscale thumbnail
+func perceptualHash(img gocv.Mat) uint64 {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	gocv.Resize(gray, &small, image.Pt(9, 8), 0, 0, gocv.InterpolationArea)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GetUCharAt(y, x) < small.GetUCharAt(y, x+1) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
@@ -0,0 +1,176 @@
+package ml
+
+import (
+	"encoding/xml"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatasetExporter(t *testing.T, settings *DatasetExportSettings) *DatasetExporter {
+	t.Helper()
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return &DatasetExporter{settings: settings, tracks: make(map[int]*flickerState)}
+}
+
+var testDatasetSample = &datasetSample{
+	name:      "cam_20261019T120000.000",
+	reason:    "uncertain",
+	timestamp: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	size:      image.Pt(200, 100),
+	objects: []datasetObject{
+		{classID: 2, className: "car", confidence: 0.4, rect: image.Rect(50, 25, 150, 75)},
+		{classID: 0, className: "person", confidence: 0.5, rect: image.Rect(0, 0, 20, 100),
+			polygon: []image.Point{{0, 0}, {20, 0}, {10, 100}}},
+	},
+}
+
+func TestWriteYOLOLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.txt")
+	if err := writeYOLOLabels(path, testDatasetSample); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2 0.500000 0.500000 0.500000 0.500000\n" +
+		"0 0.050000 0.500000 0.100000 1.000000\n"
+	if string(content) != expected {
+		t.Errorf("expected labels:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestWriteVOCAnnotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotation.xml")
+	if err := writeVOCAnnotation(path, "cam.jpg", testDatasetSample); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annotation vocAnnotation
+	if err := xml.Unmarshal(content, &annotation); err != nil {
+		t.Fatal(err)
+	}
+	if annotation.Filename != "cam.jpg" || annotation.Size != (vocSize{Width: 200, Height: 100, Depth: 3}) {
+		t.Errorf("unexpected image of annotation %+v", annotation)
+	}
+	if len(annotation.Objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(annotation.Objects))
+	}
+	// Coordinates are 1-based and inclusive
+	car := annotation.Objects[0]
+	if car.Name != "car" || car.BndBox.XMin != 51 || car.BndBox.YMin != 26 || car.BndBox.XMax != 150 || car.BndBox.YMax != 75 {
+		t.Errorf("unexpected car %+v", car)
+	}
+	if person := annotation.Objects[1]; person.Name != "person" || person.BndBox.XMin != 1 || person.BndBox.YMax != 100 {
+		t.Errorf("unexpected person %+v", person)
+	}
+}
+
+func TestCOCODatasetContinuesIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+	netClasses := []string{"person", "bicycle", "car", ""}
+	coco, err := loadCOCODataset(path, netClasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coco.Categories) != 3 || coco.Categories[2] != (cocoCategory{ID: 2, Name: "car"}) {
+		t.Errorf("unexpected categories %+v", coco.Categories)
+	}
+	coco.add("first.jpg", testDatasetSample)
+	if err := coco.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Annotations are appended after restart
+	coco, err = loadCOCODataset(path, netClasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coco.add("second.jpg", testDatasetSample)
+	if len(coco.Images) != 2 || coco.Images[1].ID != 2 || coco.Images[1].FileName != "second.jpg" {
+		t.Fatalf("unexpected images %+v", coco.Images)
+	}
+	if len(coco.Annotations) != 4 {
+		t.Fatalf("expected 4 annotations, got %d", len(coco.Annotations))
+	}
+	for i, annotation := range coco.Annotations {
+		if annotation.ID != i+1 {
+			t.Errorf("annotation %d: expected ID %d, got %d", i, i+1, annotation.ID)
+		}
+		if expected := 1 + i/2; annotation.ImageID != expected {
+			t.Errorf("annotation %d: expected image %d, got %d", i, expected, annotation.ImageID)
+		}
+	}
+	car := coco.Annotations[2]
+	if car.CategoryID != 2 || car.BBox != [4]int{50, 25, 100, 50} || car.Area != 5000 || len(car.Segmentation) != 0 {
+		t.Errorf("unexpected car annotation %+v", car)
+	}
+	if person := coco.Annotations[3]; len(person.Segmentation) != 1 || len(person.Segmentation[0]) != 6 {
+		t.Errorf("unexpected person segmentation %v", person.Segmentation)
+	}
+}
+
+func TestDatasetExporterFlickerTrigger(t *testing.T) {
+	// 10 frames per second. Track is visible when frame is true
+	tests := []struct {
+		name    string
+		visible []bool
+		// Frame index triggering export, -1 means no export
+		expected int
+	}{
+		{"stable track", []bool{true, true, true, true, true, true, true, true}, -1},
+		{"single gap", []bool{true, true, false, true, true, true}, -1},
+		{"two gaps", []bool{true, false, true, false, true, true, true}, 4},
+		{"gaps out of window", append(append([]bool{true, false, true}, make([]bool, 60)...), true), -1},
+	}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		de := newTestDatasetExporter(t, &DatasetExportSettings{FlickerMinGaps: 2, FlickerWindowSec: 5})
+		var exports []int
+		for i, visible := range test.visible {
+			de.frame++
+			var detected []*DetectedObject
+			if visible {
+				detected = []*DetectedObject{{ClassName: "person", Confidence: 0.9, TrackID: 5}}
+			}
+			if reason := de.trigger(start.Add(time.Duration(i)*100*time.Millisecond), detected); reason != "" {
+				if reason != "flicker" {
+					t.Errorf("%s: unexpected reason '%s'", test.name, reason)
+				}
+				exports = append(exports, i)
+			}
+		}
+		switch {
+		case test.expected < 0 && len(exports) != 0:
+			t.Errorf("%s: unexpected exports at frames %v", test.name, exports)
+		case test.expected >= 0 && (len(exports) != 1 || exports[0] != test.expected):
+			t.Errorf("%s: expected single export at frame %d, got %v", test.name, test.expected, exports)
+		}
+	}
+}
+
+func TestDatasetExporterDuplicateBoundary(t *testing.T) {
+	de := newTestDatasetExporter(t, &DatasetExportSettings{HashDistance: 6})
+	exported := uint64(0xF0F0F0F0F0F0F0F0)
+	de.remember(time.Now(), exported)
+	if !de.duplicate(exported ^ 0b111111) {
+		t.Error("frame at hash distance 6 must be a duplicate")
+	}
+	if de.duplicate(exported ^ 0b1111111) {
+		t.Error("frame at hash distance 7 must not be a duplicate")
+	}
+
+	de = newTestDatasetExporter(t, &DatasetExportSettings{HashDistance: -1})
+	de.remember(time.Now(), exported)
+	if de.duplicate(exported) {
+		t.Error("deduplication must be disabled")
+	}
+}
@@ -6,6 +6,8 @@ package decoder
 import "C"
 import (
 	"errors"
+	"io"
+	"log/slog"
 	"unsafe"
 
 	"github.com/ailumiyana/goav-incr/goav/avcodec"
@@ -29,6 +31,7 @@ type H264Decoder struct {
 	frame     *avutil.Frame
 	pkt       *avcodec.Packet
 	converter *converter
+	logger    *slog.Logger
 }
 
 // Frame represents decoded frame from H.264 stream
@@ -40,7 +43,12 @@ type Frame struct {
 
 // New creates new H264Decoder
 // It accepts expected pixel format for the output which
-func New(pxlFmt PixelFormat) (*H264Decoder, error) {
+// and logger for decoding diagnostics (nil disables logging)
+func New(pxlFmt PixelFormat, logger *slog.Logger) (*H264Decoder, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
 	avcodec.AvcodecRegisterAll()
 	codec := avcodec.AvcodecFindDecoder(avcodec.CodecId(avcodec.AV_CODEC_ID_H264))
 	if codec == nil {
@@ -90,6 +98,7 @@ func New(pxlFmt PixelFormat) (*H264Decoder, error) {
 		frame:     frame,
 		pkt:       pkt,
 		converter: converter,
+		logger:    logger,
 	}
 
 	return h, nil
@@ -106,6 +115,9 @@ func (h *H264Decoder) Decode(data []byte) (*Frame, error) {
 	if err != nil && nread < 0 {
 		return nil, err
 	}
+	if err != nil {
+		h.logger.Debug("Can't decode frame", "error", err, "bytes", len(data), "parsed", nread)
+	}
 
 	if isFrameAvailable && frame != nil {
 		return frame, nil
@@ -115,6 +127,28 @@ func (h *H264Decoder) Decode(data []byte) (*Frame, error) {
 	return nil, nil
 }
 
+// DecodeStream parses the beginning of continuous H.264 byte stream (e.g. read from file)
+// It returns decoded frame (nil if more data is needed) and number of consumed bytes.
+// Remaining data must be passed to the next call
+func (h *H264Decoder) DecodeStream(data []byte) (*Frame, int, error) {
+	if len(data) <= 0 {
+		return nil, 0, nil
+	}
+
+	frame, nread, isFrameAvailable, err := h.decodeFrameImpl(data)
+	if nread < 0 {
+		return nil, 0, err
+	}
+	if err != nil {
+		h.logger.Debug("Can't decode frame", "error", err, "bytes", len(data), "parsed", nread)
+		return nil, nread, nil
+	}
+	if !isFrameAvailable {
+		return nil, nread, nil
+	}
+	return frame, nread, nil
+}
+
 // Close free ups memory used for decoder structures
 // It needs to be called to prevent memory leaks
 func (h *H264Decoder) Close() {
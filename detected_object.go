@@ -1,6 +1,7 @@
 package ml
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
 
@@ -30,16 +31,100 @@ type DetectedObject struct {
 	ClassName string
 	// The probability that an object belongs to the specified class
 	Confidence float32
+	// Identifier of track assigned by Tracker (0 if object is not tracked)
+	TrackID int
+	// Results of second-stage classifiers by classifier name
+	Attributes map[string]Attribute
+	// Instance mask (segmentation models only)
+	Mask *Mask
+	// Body keypoints in COCO order (pose models only)
+	Keypoints []Keypoint
 
 	// Unexported
 	speed float32
 }
 
+// Translate Moves box and mask of object by offset
+func (d *DetectedObject) Translate(offset image.Point) {
+	d.Rect = d.Rect.Add(offset)
+	if d.Mask != nil {
+		d.Mask.Translate(offset)
+	}
+	for i := range d.Keypoints {
+		d.Keypoints[i].Point = d.Keypoints[i].Point.Add(offset)
+	}
+}
+
+// Scale Maps box and mask of object from coordinates of frame with size 'from' to coordinates of frame with size 'to'
+func (d *DetectedObject) Scale(from, to image.Point) {
+	d.Rect = scaleRect(d.Rect, from, to)
+	if d.Mask != nil {
+		d.Mask.Scale(from, to)
+	}
+	for i := range d.Keypoints {
+		p := d.Keypoints[i].Point
+		d.Keypoints[i].Point = scaleRect(image.Rectangle{Min: p, Max: p}, from, to).Min
+	}
+}
+
 // String returns something we call 'hash' for detected object
 func (d *DetectedObject) String() string {
 	return fmt.Sprintf("DetectedObject{classID: %d, conf: %.5f, rect: ((%d, %d), (%d, %d))}", d.ClassID, d.Confidence, d.Rect.Min.X, d.Rect.Min.Y, d.Rect.Max.X, d.Rect.Max.Y)
 }
 
+// detectedObjectJSON JSON representation of DetectedObject
+type detectedObjectJSON struct {
+	ClassID    int     `json:"class_id"`
+	ClassName  string  `json:"class_name"`
+	Confidence float32 `json:"confidence"`
+	TrackID    int     `json:"track_id,omitempty"`
+	// Bounding box as [x, y, width, height]
+	BBox       [4]int               `json:"bbox"`
+	Attributes map[string]Attribute `json:"attributes,omitempty"`
+	Mask       *maskJSON            `json:"mask,omitempty"`
+	Keypoints  []Keypoint           `json:"keypoints,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (d *DetectedObject) MarshalJSON() ([]byte, error) {
+	v := detectedObjectJSON{
+		ClassID:    d.ClassID,
+		ClassName:  d.ClassName,
+		Confidence: d.Confidence,
+		TrackID:    d.TrackID,
+		BBox:       [4]int{d.Rect.Min.X, d.Rect.Min.Y, d.Rect.Dx(), d.Rect.Dy()},
+		Attributes: d.Attributes,
+		Keypoints:  d.Keypoints,
+	}
+	if d.Mask != nil {
+		v.Mask = d.Mask.toJSON()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *DetectedObject) UnmarshalJSON(data []byte) error {
+	var v detectedObjectJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	d.ClassID = v.ClassID
+	d.ClassName = v.ClassName
+	d.Confidence = v.Confidence
+	d.TrackID = v.TrackID
+	d.Attributes = v.Attributes
+	d.Keypoints = v.Keypoints
+	if v.Mask != nil {
+		mask, err := maskFromJSON(v.Mask)
+		if err != nil {
+			return errors.Wrap(err, "Invalid mask")
+		}
+		d.Mask = mask
+	}
+	d.Rect = image.Rect(v.BBox[0], v.BBox[1], v.BBox[0]+v.BBox[2], v.BBox[1]+v.BBox[3])
+	return nil
+}
+
 // DetectObjects Detect objects for provided Go's image via neural network
 //
 // app - Application instance containing pointer to neural network for object detection
@@ -48,20 +133,15 @@ func (d *DetectedObject) String() string {
 // filters - List of classes for which you need to filter detected objects
 //
 func DetectObjects(app *Application, img gocv.Mat, netClasses []string, filters ...string) ([]*DetectedObject, error) {
-	blobImg := gocv.BlobFromImage(img, yoloScaleFactor, yoloSize, yoloMean, true, false)
-	defer blobImg.Close()
-
-	app.neuralNetwork.SetInput(blobImg, yoloBlobName)
-	detections := app.neuralNetwork.ForwardLayers(app.layersNames)
-	detected, err := postprocess(detections, 0.5, 0.4, float32(img.Cols()), float32(img.Rows()), netClasses, filters)
-
-	for i := range detections {
-		err := detections[i].Close()
-		if err != nil {
-			return detected, errors.Wrap(err, "Can't deallocate gocv.Mat")
-		}
+	app.settings.RLock()
+	params := &DetectionParams{
+		NetClasses:    netClasses,
+		Filters:       filters,
+		ConfThreshold: float32(app.settings.NeuralNetworkSettings.ConfThreshold),
+		NmsThreshold:  float32(app.settings.NeuralNetworkSettings.NmsThreshold),
 	}
-	return detected, err
+	app.settings.RUnlock()
+	return app.detector.Detect(img, params)
 }
 
 func postprocess(detections []gocv.Mat, confidenceThreshold, nmsThreshold float32, frameWidth, frameHeight float32, netClasses []string, filters []string) ([]*DetectedObject, error) {
@@ -95,8 +175,13 @@ func postprocess(detections []gocv.Mat, confidenceThreshold, nmsThreshold float3
 			}
 		}
 	}
+	return applyNMS(detectedObjects, bboxes, confidences, confidenceThreshold, nmsThreshold), nil
+}
+
+// applyNMS Filters overlapping detections by non-maximum suppression
+func applyNMS(detectedObjects []*DetectedObject, bboxes []image.Rectangle, confidences []float32, confidenceThreshold, nmsThreshold float32) []*DetectedObject {
 	if len(bboxes) == 0 {
-		return nil, nil
+		return nil
 	}
 	indices := make([]int, len(bboxes))
 	for i := range indices {
@@ -112,7 +197,7 @@ func postprocess(detections []gocv.Mat, confidenceThreshold, nmsThreshold float3
 		}
 		filteredDetectedObjects = append(filteredDetectedObjects, detectedObjects[idx])
 	}
-	return filteredDetectedObjects, nil
+	return filteredDetectedObjects
 }
 
 func getClassIDAndConfidence(x []float32) (int, float32) {
@@ -0,0 +1,117 @@
+package ml
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// DetectionParams Parameters of postprocessing of network output for single image
+type DetectionParams struct {
+	NetClasses    []string
+	Filters       []string
+	ConfThreshold float32
+	NmsThreshold  float32
+}
+
+// Detector Runs neural network on images. Implementations must be safe for concurrent use
+type Detector interface {
+	// Detect returns objects detected on image
+	Detect(img gocv.Mat, params *DetectionParams) ([]*DetectedObject, error)
+	// Close Frees memory of neural network
+	Close() error
+}
+
+// NewDetector Creates detector for model type from settings
+func NewDetector(settings *NeuralNetworkSettings) (Detector, error) {
+	switch settings.ModelType {
+	case "darknet":
+		return NewNetDetector(settings)
+	case "yolov8-pose":
+		return NewPoseDetector(settings)
+	default:
+		return NewSegmentationDetector(settings)
+	}
+}
+
+// yoloNet YOLO network with names of its output layers
+type yoloNet struct {
+	net         gocv.Net
+	layersNames []string
+}
+
+// newYoloNet Reads Darknet network and configures backend and target
+func newYoloNet(settings *NeuralNetworkSettings) (*yoloNet, error) {
+	net, layersNames, err := readNet(settings.DarknetWeights, settings.DarknetCFG, settings.Backend, settings.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &yoloNet{net: net, layersNames: layersNames}, nil
+}
+
+// readNet Reads model of any format supported by OpenCV and returns it with names of its output layers
+func readNet(model, config, backend, target string) (gocv.Net, []string, error) {
+	net := gocv.ReadNet(model, config)
+	if net.Empty() {
+		return net, nil, errors.Errorf("Can't read model %s", model)
+	}
+	layersNames := make([]string, 0, 3)
+	for _, idx := range net.GetUnconnectedOutLayers() {
+		layer := net.GetLayer(idx)
+		layersNames = append(layersNames, layer.GetName())
+	}
+	if err := net.SetPreferableBackend(gocv.ParseNetBackend(backend)); err != nil {
+		_ = net.Close()
+		return net, nil, errors.Wrapf(err, "Can't set backend %s", backend)
+	}
+	if err := net.SetPreferableTarget(gocv.ParseNetTarget(target)); err != nil {
+		_ = net.Close()
+		return net, nil, errors.Wrapf(err, "Can't set target %s", target)
+	}
+	return net, layersNames, nil
+}
+
+// forward Runs network on blob and returns outputs of YOLO layers
+func (yn *yoloNet) forward(blob gocv.Mat) []gocv.Mat {
+	yn.net.SetInput(blob, yoloBlobName)
+	return yn.net.ForwardLayers(yn.layersNames)
+}
+
+// NetDetector Detector running network on every image separately
+type NetDetector struct {
+	mu  sync.Mutex
+	net *yoloNet
+}
+
+// NewNetDetector Creates NetDetector with network from settings
+func NewNetDetector(settings *NeuralNetworkSettings) (*NetDetector, error) {
+	net, err := newYoloNet(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &NetDetector{net: net}, nil
+}
+
+// Detect implements Detector
+func (nd *NetDetector) Detect(img gocv.Mat, params *DetectionParams) ([]*DetectedObject, error) {
+	blob := gocv.BlobFromImage(img, yoloScaleFactor, yoloSize, yoloMean, true, false)
+	defer blob.Close()
+
+	nd.mu.Lock()
+	detections := nd.net.forward(blob)
+	nd.mu.Unlock()
+	defer closeMats(detections)
+	return postprocess(detections, params.ConfThreshold, params.NmsThreshold, float32(img.Cols()), float32(img.Rows()), params.NetClasses, params.Filters)
+}
+
+// Close implements Detector
+func (nd *NetDetector) Close() error {
+	return nd.net.net.Close()
+}
+
+func closeMats(mats []gocv.Mat) {
+	for i := range mats {
+		_ = mats[i].Close()
+	}
+}
@@ -0,0 +1,50 @@
+package ml
+
+import (
+	"os"
+	"runtime"
+
+	"gocv.io/x/gocv"
+)
+
+const keyEscape = 27
+
+// DisplaySink Shows annotated frames in imshow() window. Pressing 'ESC' in window calls stop function
+type DisplaySink struct {
+	window *gocv.Window
+	stop   func()
+}
+
+// NewDisplaySink Opens window of provided size. Requires graphical environment
+func NewDisplaySink(name string, width, height int, stop func()) *DisplaySink {
+	window := gocv.NewWindow(name)
+	window.ResizeWindow(width, height)
+	return &DisplaySink{
+		window: window,
+		stop:   stop,
+	}
+}
+
+// Consume implements FrameSink
+func (ds *DisplaySink) Consume(frame *FrameData, _ []*DetectedObject) error {
+	ds.window.IMShow(frame.ImgScaled)
+	if ds.window.WaitKey(1) == keyEscape {
+		ds.stop()
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (ds *DisplaySink) Close() error {
+	return ds.window.Close()
+}
+
+// displayAvailable Checks whether graphical environment is available for imshow() window
+func displayAvailable() bool {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		return true
+	default:
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+}
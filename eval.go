@@ -0,0 +1,237 @@
+package ml
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Extensions of images of evaluation datasets
+var evalImageExtensions = []string{".jpg", ".jpeg", ".png", ".bmp"}
+
+// Annotation Ground truth object of evaluation dataset
+type Annotation struct {
+	ClassName string
+	// Box as [x, y, width, height]. Coordinates are normalized to [0..1] by image size when Normalized is set
+	Box        [4]float64
+	Normalized bool
+}
+
+// Rect returns box of annotation for image of provided size
+func (a *Annotation) Rect(size image.Point) image.Rectangle {
+	x, y, w, h := a.Box[0], a.Box[1], a.Box[2], a.Box[3]
+	if a.Normalized {
+		x, w = x*float64(size.X), w*float64(size.X)
+		y, h = y*float64(size.Y), h*float64(size.Y)
+	}
+	return image.Rect(int(x), int(y), int(x+w), int(y+h))
+}
+
+// EvalSample Image of evaluation dataset with its ground truth
+type EvalSample struct {
+	ImagePath   string
+	Annotations []Annotation
+}
+
+// LoadYOLODataset Loads images and YOLO txt labels ('class cx cy w h' per line, normalized). Label files are matched to images by base name
+func LoadYOLODataset(imagesDir, labelsDir string, classes []string) ([]*EvalSample, error) {
+	images, err := ListImages(imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]*EvalSample, 0, len(images))
+	for _, path := range images {
+		labels, err := readYOLOFile(filepath.Join(labelsDir, baseName(path)+".txt"), classes, false)
+		if err != nil && !os.IsNotExist(errors.Cause(err)) {
+			return nil, err
+		}
+		sample := &EvalSample{ImagePath: path}
+		for _, label := range labels {
+			sample.Annotations = append(sample.Annotations, label.Annotation)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// LoadCOCODataset Loads COCO JSON annotations. Paths of images are resolved relatively to imagesDir. Crowd annotations are skipped
+func LoadCOCODataset(imagesDir, annotationsFile string) ([]*EvalSample, error) {
+	content, err := os.ReadFile(annotationsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't read COCO annotations")
+	}
+	var coco struct {
+		Images []struct {
+			ID       int    `json:"id"`
+			FileName string `json:"file_name"`
+		} `json:"images"`
+		Annotations []struct {
+			ImageID    int        `json:"image_id"`
+			CategoryID int        `json:"category_id"`
+			BBox       [4]float64 `json:"bbox"`
+			IsCrowd    int        `json:"iscrowd"`
+		} `json:"annotations"`
+		Categories []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"categories"`
+	}
+	if err := json.Unmarshal(content, &coco); err != nil {
+		return nil, errors.Wrap(err, "Can't parse COCO annotations")
+	}
+	categories := make(map[int]string, len(coco.Categories))
+	for _, category := range coco.Categories {
+		categories[category.ID] = category.Name
+	}
+	samples := make([]*EvalSample, 0, len(coco.Images))
+	byID := make(map[int]*EvalSample, len(coco.Images))
+	for _, img := range coco.Images {
+		sample := &EvalSample{ImagePath: filepath.Join(imagesDir, img.FileName)}
+		samples = append(samples, sample)
+		byID[img.ID] = sample
+	}
+	for _, annotation := range coco.Annotations {
+		sample, ok := byID[annotation.ImageID]
+		if !ok || annotation.IsCrowd != 0 {
+			continue
+		}
+		name, ok := categories[annotation.CategoryID]
+		if !ok {
+			return nil, fmt.Errorf("unknown category %d", annotation.CategoryID)
+		}
+		sample.Annotations = append(sample.Annotations, Annotation{ClassName: name, Box: annotation.BBox})
+	}
+	return samples, nil
+}
+
+// LoadYOLOPredictions Reads detections stored in YOLO txt format with confidence ('class cx cy w h confidence' per line)
+func LoadYOLOPredictions(path string, classes []string, size image.Point) ([]*DetectedObject, error) {
+	labels, err := readYOLOFile(path, classes, true)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	detected := make([]*DetectedObject, 0, len(labels))
+	for _, label := range labels {
+		detected = append(detected, &DetectedObject{
+			Rect:       label.Rect(size),
+			ClassID:    label.classID,
+			ClassName:  label.ClassName,
+			Confidence: label.confidence,
+		})
+	}
+	return detected, nil
+}
+
+// EvalDetectFunc Runs detection on image of sample
+type EvalDetectFunc func(sample *EvalSample, img gocv.Mat) ([]*DetectedObject, error)
+
+// Evaluate Runs detection on every sample and computes metrics against ground truth
+func Evaluate(samples []*EvalSample, detect EvalDetectFunc) (*EvalReport, error) {
+	results := make([]EvalResult, 0, len(samples))
+	for _, sample := range samples {
+		img := gocv.IMRead(sample.ImagePath, gocv.IMReadColor)
+		if img.Empty() {
+			_ = img.Close()
+			return nil, fmt.Errorf("can't read image %s", sample.ImagePath)
+		}
+		detected, err := detect(sample, img)
+		size := image.Pt(img.Cols(), img.Rows())
+		_ = img.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't detect objects on %s", sample.ImagePath)
+		}
+		result := EvalResult{Detections: detected}
+		for i := range sample.Annotations {
+			result.GroundTruth = append(result.GroundTruth, GroundTruthObject{
+				ClassName: sample.Annotations[i].ClassName,
+				Rect:      sample.Annotations[i].Rect(size),
+			})
+		}
+		results = append(results, result)
+	}
+	return ComputeEvalReport(results), nil
+}
+
+type yoloLabel struct {
+	Annotation
+	classID    int
+	confidence float32
+}
+
+func readYOLOFile(path string, classes []string, withConfidence bool) ([]yoloLabel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't open %s", path)
+	}
+	defer f.Close()
+
+	var labels []yoloLabel
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		expected := 5
+		if withConfidence {
+			expected = 6
+		}
+		if len(fields) != expected {
+			return nil, fmt.Errorf("%s:%d: expected %d fields, got %d", path, line, expected, len(fields))
+		}
+		classID, err := strconv.Atoi(fields[0])
+		if err != nil || classID < 0 || classID >= len(classes) {
+			return nil, fmt.Errorf("%s:%d: invalid class '%s'", path, line, fields[0])
+		}
+		var values [5]float64
+		for i := 1; i < expected; i++ {
+			if values[i-1], err = strconv.ParseFloat(fields[i], 64); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid number '%s'", path, line, fields[i])
+			}
+		}
+		cx, cy, w, h := values[0], values[1], values[2], values[3]
+		labels = append(labels, yoloLabel{
+			Annotation: Annotation{
+				ClassName:  classes[classID],
+				Box:        [4]float64{cx - w/2, cy - h/2, w, h},
+				Normalized: true,
+			},
+			classID:    classID,
+			confidence: float32(values[4]),
+		})
+	}
+	return labels, scanner.Err()
+}
+
+// ListImages Lists images in directory (not recursively) sorted by name
+func ListImages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't read directory %s", dir)
+	}
+	var images []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && stringInSlice(&ext, evalImageExtensions) {
+			images = append(images, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,205 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// IoU thresholds for mAP@0.5:0.95
+var evalIoUThresholds = []float64{0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+// Number of recall points for interpolated average precision (COCO style)
+const evalRecallPoints = 101
+
+// GroundTruthObject Ground truth box resolved to pixel coordinates
+type GroundTruthObject struct {
+	ClassName string
+	Rect      image.Rectangle
+}
+
+// EvalResult Ground truth and detections of single image
+type EvalResult struct {
+	GroundTruth []GroundTruthObject
+	Detections  []*DetectedObject
+}
+
+// ClassMetrics Evaluation metrics of single class
+type ClassMetrics struct {
+	ClassName   string  `json:"class_name"`
+	GroundTruth int     `json:"ground_truth"`
+	Detections  int     `json:"detections"`
+	Precision   float64 `json:"precision"`
+	Recall      float64 `json:"recall"`
+	AP50        float64 `json:"ap50"`
+	AP50_95     float64 `json:"ap50_95"`
+}
+
+// EvalReport Evaluation metrics of dataset. Precision and recall are calculated at IoU 0.5
+type EvalReport struct {
+	Images    int            `json:"images"`
+	Precision float64        `json:"precision"`
+	Recall    float64        `json:"recall"`
+	MAP50     float64        `json:"map50"`
+	MAP50_95  float64        `json:"map50_95"`
+	Classes   []ClassMetrics `json:"classes"`
+}
+
+// WriteText Writes human readable report
+func (report *EvalReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "class\tgt\tdet\tprecision\trecall\tAP@0.5\tAP@0.5:0.95\t")
+	for _, class := range report.Classes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t\n", class.ClassName, class.GroundTruth, class.Detections, class.Precision, class.Recall, class.AP50, class.AP50_95)
+	}
+	fmt.Fprintf(tw, "all (%d images)\t\t\t%.4f\t%.4f\t%.4f\t%.4f\t\n", report.Images, report.Precision, report.Recall, report.MAP50, report.MAP50_95)
+	return tw.Flush()
+}
+
+// ComputeEvalReport Computes per class precision/recall, AP@0.5 and AP@0.5:0.95. Classes without ground truth are skipped from mean values
+func ComputeEvalReport(results []EvalResult) *EvalReport {
+	classes := make(map[string]struct{})
+	for i := range results {
+		for _, gt := range results[i].GroundTruth {
+			classes[gt.ClassName] = struct{}{}
+		}
+		for _, det := range results[i].Detections {
+			classes[det.ClassName] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &EvalReport{Images: len(results), Classes: make([]ClassMetrics, 0, len(names))}
+	evaluated := 0
+	totalGT, totalDet, totalTP := 0, 0, 0
+	for _, name := range names {
+		metrics := ClassMetrics{ClassName: name}
+		var tp int
+		for i, threshold := range evalIoUThresholds {
+			matches, gtCount := matchDetections(results, name, threshold)
+			ap := averagePrecision(matches, gtCount)
+			metrics.AP50_95 += ap / float64(len(evalIoUThresholds))
+			if i == 0 {
+				metrics.AP50 = ap
+				metrics.GroundTruth = gtCount
+				metrics.Detections = len(matches)
+				for _, match := range matches {
+					if match.tp {
+						tp++
+					}
+				}
+			}
+		}
+		if metrics.Detections > 0 {
+			metrics.Precision = float64(tp) / float64(metrics.Detections)
+		}
+		if metrics.GroundTruth > 0 {
+			metrics.Recall = float64(tp) / float64(metrics.GroundTruth)
+			report.MAP50 += metrics.AP50
+			report.MAP50_95 += metrics.AP50_95
+			evaluated++
+		}
+		totalGT += metrics.GroundTruth
+		totalDet += metrics.Detections
+		totalTP += tp
+		report.Classes = append(report.Classes, metrics)
+	}
+	if evaluated > 0 {
+		report.MAP50 /= float64(evaluated)
+		report.MAP50_95 /= float64(evaluated)
+	}
+	if totalDet > 0 {
+		report.Precision = float64(totalTP) / float64(totalDet)
+	}
+	if totalGT > 0 {
+		report.Recall = float64(totalTP) / float64(totalGT)
+	}
+	return report
+}
+
+type evalMatch struct {
+	confidence float32
+	tp         bool
+}
+
+// matchDetections Greedily matches detections of class (by descending confidence) to ground truth boxes of the same image with the highest IoU
+func matchDetections(results []EvalResult, className string, threshold float64) ([]evalMatch, int) {
+	var matches []evalMatch
+	gtCount := 0
+	for i := range results {
+		var gts []image.Rectangle
+		for _, gt := range results[i].GroundTruth {
+			if gt.ClassName == className {
+				gts = append(gts, gt.Rect)
+			}
+		}
+		gtCount += len(gts)
+		var dets []*DetectedObject
+		for _, det := range results[i].Detections {
+			if det.ClassName == className {
+				dets = append(dets, det)
+			}
+		}
+		sort.SliceStable(dets, func(a, b int) bool {
+			return dets[a].Confidence > dets[b].Confidence
+		})
+		used := make([]bool, len(gts))
+		for _, det := range dets {
+			best, bestIoU := -1, threshold
+			for j, gt := range gts {
+				if used[j] {
+					continue
+				}
+				if iou := IoU(det.Rect, gt); iou >= bestIoU {
+					best, bestIoU = j, iou
+				}
+			}
+			if best >= 0 {
+				used[best] = true
+			}
+			matches = append(matches, evalMatch{confidence: det.Confidence, tp: best >= 0})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].confidence > matches[b].confidence
+	})
+	return matches, gtCount
+}
+
+// averagePrecision Area under precision envelope sampled at evenly spaced recall points
+func averagePrecision(matches []evalMatch, gtCount int) float64 {
+	if gtCount == 0 || len(matches) == 0 {
+		return 0
+	}
+	recall := make([]float64, len(matches))
+	precision := make([]float64, len(matches))
+	tp := 0
+	for i, match := range matches {
+		if match.tp {
+			tp++
+		}
+		recall[i] = float64(tp) / float64(gtCount)
+		precision[i] = float64(tp) / float64(i+1)
+	}
+	for i := len(precision) - 2; i >= 0; i-- {
+		precision[i] = max(precision[i], precision[i+1])
+	}
+	sum := 0.0
+	j := 0
+	for k := 0; k < evalRecallPoints; k++ {
+		r := float64(k) / float64(evalRecallPoints-1)
+		for j < len(recall) && recall[j] < r {
+			j++
+		}
+		if j < len(recall) {
+			sum += precision[j]
+		}
+	}
+	return sum / evalRecallPoints
+}
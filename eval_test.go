@@ -0,0 +1,176 @@
+package ml
+
+import (
+	"image"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+const evalTestdata = "testdata/eval"
+
+var evalTestClasses = []string{"person", "car"}
+
+// Size of images of bundled dataset
+var evalTestImageSize = image.Pt(64, 48)
+
+func loadEvalTestdata(t *testing.T) []*EvalSample {
+	t.Helper()
+	samples, err := LoadYOLODataset(filepath.Join(evalTestdata, "images"), filepath.Join(evalTestdata, "labels"), evalTestClasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+	return samples
+}
+
+// predictionsDetector Fake detector reading predictions of bundled dataset
+func predictionsDetector(sample *EvalSample, img gocv.Mat) ([]*DetectedObject, error) {
+	path := filepath.Join(evalTestdata, "predictions", baseName(sample.ImagePath)+".txt")
+	return LoadYOLOPredictions(path, evalTestClasses, image.Pt(img.Cols(), img.Rows()))
+}
+
+func assertClose(t *testing.T, name string, actual, expected float64) {
+	t.Helper()
+	if math.Abs(actual-expected) > 1e-4 {
+		t.Errorf("%s: expected %.4f, got %.4f", name, expected, actual)
+	}
+}
+
+// assertBundledReport Checks metrics of bundled dataset. Person: 2 of 3 objects found with exact boxes and 1 false positive with the lowest confidence.
+// Car: both objects found, second one by box with IoU 0.78 and by exact box with lower confidence (false positive at IoU below 0.8, true positive above)
+func assertBundledReport(t *testing.T, report *EvalReport) {
+	t.Helper()
+	if report.Images != 3 {
+		t.Errorf("expected 3 images, got %d", report.Images)
+	}
+	expected := []ClassMetrics{
+		{ClassName: "car", GroundTruth: 2, Detections: 3, Precision: 2.0 / 3, Recall: 1, AP50: 1, AP50_95: (6 + 4*(51+50*2.0/3)/101) / 10},
+		{ClassName: "person", GroundTruth: 3, Detections: 3, Precision: 2.0 / 3, Recall: 2.0 / 3, AP50: 67.0 / 101, AP50_95: 67.0 / 101},
+	}
+	if len(report.Classes) != len(expected) {
+		t.Fatalf("expected %d classes, got %d", len(expected), len(report.Classes))
+	}
+	for i, e := range expected {
+		a := report.Classes[i]
+		if a.ClassName != e.ClassName || a.GroundTruth != e.GroundTruth || a.Detections != e.Detections {
+			t.Errorf("class %d: expected %+v, got %+v", i, e, a)
+			continue
+		}
+		assertClose(t, e.ClassName+" precision", a.Precision, e.Precision)
+		assertClose(t, e.ClassName+" recall", a.Recall, e.Recall)
+		assertClose(t, e.ClassName+" AP@0.5", a.AP50, e.AP50)
+		assertClose(t, e.ClassName+" AP@0.5:0.95", a.AP50_95, e.AP50_95)
+	}
+	assertClose(t, "precision", report.Precision, 4.0/6)
+	assertClose(t, "recall", report.Recall, 4.0/5)
+	assertClose(t, "mAP@0.5", report.MAP50, (expected[0].AP50+expected[1].AP50)/2)
+	assertClose(t, "mAP@0.5:0.95", report.MAP50_95, (expected[0].AP50_95+expected[1].AP50_95)/2)
+}
+
+func TestEvaluateBundledDataset(t *testing.T) {
+	report, err := Evaluate(loadEvalTestdata(t), predictionsDetector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBundledReport(t, report)
+}
+
+func TestComputeEvalReportBundledDataset(t *testing.T) {
+	var results []EvalResult
+	for _, sample := range loadEvalTestdata(t) {
+		path := filepath.Join(evalTestdata, "predictions", baseName(sample.ImagePath)+".txt")
+		detected, err := LoadYOLOPredictions(path, evalTestClasses, evalTestImageSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := EvalResult{Detections: detected}
+		for i := range sample.Annotations {
+			result.GroundTruth = append(result.GroundTruth, GroundTruthObject{
+				ClassName: sample.Annotations[i].ClassName,
+				Rect:      sample.Annotations[i].Rect(evalTestImageSize),
+			})
+		}
+		results = append(results, result)
+	}
+	assertBundledReport(t, ComputeEvalReport(results))
+}
+
+func TestAveragePrecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		matches  []evalMatch
+		gtCount  int
+		expected float64
+	}{
+		{"no ground truth", []evalMatch{{0.9, false}}, 0, 0},
+		{"no detections", nil, 2, 0},
+		{"perfect", []evalMatch{{0.9, true}, {0.8, true}}, 2, 1},
+		{"half recall", []evalMatch{{0.9, true}}, 2, 51.0 / 101},
+		{"false positive first", []evalMatch{{0.9, false}, {0.8, true}}, 1, 0.5},
+		// Precision envelope: 1 up to recall 0.5, 2/3 up to recall 1
+		{"false positive between", []evalMatch{{0.9, true}, {0.8, false}, {0.7, true}}, 2, (51 + 50*2.0/3) / 101},
+		{"only false positives", []evalMatch{{0.9, false}, {0.8, false}}, 3, 0},
+	}
+	for _, tt := range tests {
+		assertClose(t, tt.name, averagePrecision(tt.matches, tt.gtCount), tt.expected)
+	}
+}
+
+func TestMatchDetections(t *testing.T) {
+	r := image.Rect
+	results := []EvalResult{
+		{
+			GroundTruth: []GroundTruthObject{{"person", r(0, 0, 10, 10)}, {"person", r(20, 0, 30, 10)}, {"car", r(40, 0, 60, 10)}},
+			Detections: []*DetectedObject{
+				// Duplicate of the first person with lower confidence is false positive
+				{Rect: r(0, 0, 10, 10), ClassName: "person", Confidence: 0.5},
+				{Rect: r(1, 0, 11, 10), ClassName: "person", Confidence: 0.9},
+				// Box of wrong class doesn't match
+				{Rect: r(20, 0, 30, 10), ClassName: "car", Confidence: 0.8},
+				// IoU 0.67 with the second person
+				{Rect: r(22, 0, 32, 10), ClassName: "person", Confidence: 0.7},
+			},
+		},
+		{
+			GroundTruth: []GroundTruthObject{{"person", r(0, 0, 10, 10)}},
+			// Boxes are matched within the same image only
+			Detections: []*DetectedObject{{Rect: r(20, 0, 30, 10), ClassName: "person", Confidence: 0.6}},
+		},
+	}
+
+	matches, gtCount := matchDetections(results, "person", 0.5)
+	if gtCount != 3 {
+		t.Errorf("expected 3 ground truth persons, got %d", gtCount)
+	}
+	expected := []evalMatch{{0.9, true}, {0.7, true}, {0.6, false}, {0.5, false}}
+	if len(matches) != len(expected) {
+		t.Fatalf("expected %d matches, got %d", len(expected), len(matches))
+	}
+	for i := range expected {
+		if matches[i] != expected[i] {
+			t.Errorf("match %d: expected %+v, got %+v", i, expected[i], matches[i])
+		}
+	}
+
+	// Stricter threshold rejects shifted boxes
+	matches, _ = matchDetections(results, "person", 0.85)
+	tp := 0
+	for _, match := range matches {
+		if match.tp {
+			tp++
+		}
+	}
+	if tp != 1 {
+		t.Errorf("expected 1 true positive at IoU 0.85, got %d", tp)
+	}
+
+	matches, gtCount = matchDetections(results, "car", 0.5)
+	if gtCount != 1 || len(matches) != 1 || matches[0].tp {
+		t.Errorf("car: expected single false positive for 1 ground truth, got %+v of %d", matches, gtCount)
+	}
+}
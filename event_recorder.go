@@ -0,0 +1,275 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+const eventFilePrefix = "event_"
+
+// Layout of clip's timestamp in file names
+const eventTimeLayout = "20060102T150405.000"
+
+// bufferedFrame JPEG encoded frame kept in pre-roll buffer
+type bufferedFrame struct {
+	timestamp time.Time
+	data      []byte
+}
+
+// eventFrameRecord Detections of a single frame written to the sidecar
+type eventFrameRecord struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Objects   []*DetectedObject `json:"objects"`
+}
+
+// eventSidecar JSON sidecar of recorded clip
+type eventSidecar struct {
+	Stream    string              `json:"stream"`
+	Video     string              `json:"video"`
+	StartedAt time.Time           `json:"started_at"`
+	EndedAt   time.Time           `json:"ended_at"`
+	Width     int                 `json:"width"`
+	Height    int                 `json:"height"`
+	Frames    []*eventFrameRecord `json:"frames"`
+}
+
+// EventRecorder Saves video clips of detection events with pre-roll and post-roll
+type EventRecorder struct {
+	settings   *EventRecorderSettings
+	streamName string
+	logger     *slog.Logger
+
+	preRoll  []bufferedFrame
+	writer   *gocv.VideoWriter
+	sidecar  *eventSidecar
+	basePath string
+	lastSeen time.Time
+}
+
+// NewEventRecorder Creates EventRecorder writing clips to configured directory
+func NewEventRecorder(settings *EventRecorderSettings, streamName string, logger *slog.Logger) (*EventRecorder, error) {
+	if err := os.MkdirAll(settings.Directory, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "Can't create events directory %s", settings.Directory)
+	}
+	return &EventRecorder{
+		settings:   settings,
+		streamName: streamName,
+		logger:     logger.With("component", "event_recorder"),
+	}, nil
+}
+
+// Consume implements FrameSink
+func (er *EventRecorder) Consume(frame *FrameData, detected []*DetectedObject) error {
+	img := frame.ImgScaled
+	if er.settings.Source == "raw" {
+		img = frame.ImgSource
+	}
+	triggered := er.isTriggered(detected)
+
+	if er.writer == nil {
+		if !triggered {
+			return er.buffer(frame.Timestamp, img)
+		}
+		if err := er.start(frame.Timestamp, img); err != nil {
+			return err
+		}
+	}
+
+	if triggered {
+		er.lastSeen = frame.Timestamp
+	}
+	if len(detected) != 0 {
+		er.sidecar.Frames = append(er.sidecar.Frames, &eventFrameRecord{
+			Timestamp: frame.Timestamp,
+			Objects:   detected,
+		})
+	}
+	if err := er.writer.Write(img); err != nil {
+		return errors.Wrap(err, "Can't write frame to event clip")
+	}
+	er.sidecar.EndedAt = frame.Timestamp
+
+	if frame.Timestamp.Sub(er.lastSeen).Seconds() > er.settings.PostRollSec {
+		return er.finish()
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (er *EventRecorder) Close() error {
+	er.preRoll = nil
+	if er.writer != nil {
+		return er.finish()
+	}
+	return nil
+}
+
+func (er *EventRecorder) isTriggered(detected []*DetectedObject) bool {
+	for _, detection := range detected {
+		if len(er.settings.TriggerClasses) == 0 || stringInSlice(&detection.ClassName, er.settings.TriggerClasses) {
+			return true
+		}
+	}
+	return false
+}
+
+// buffer Keeps encoded frame in pre-roll buffer and drops frames older than pre-roll
+func (er *EventRecorder) buffer(timestamp time.Time, img gocv.Mat) error {
+	if er.settings.PreRollSec == 0 {
+		return nil
+	}
+	data, err := EncodeImage(gocv.JPEGFileExt, img, gocv.IMWriteJpegQuality, er.settings.BufferQuality)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode frame for pre-roll buffer")
+	}
+	er.preRoll = append(er.preRoll, bufferedFrame{timestamp: timestamp, data: data})
+
+	drop := 0
+	for drop < len(er.preRoll) && timestamp.Sub(er.preRoll[drop].timestamp).Seconds() > er.settings.PreRollSec {
+		drop++
+	}
+	er.preRoll = er.preRoll[drop:]
+	return nil
+}
+
+// start Opens new clip and writes pre-roll buffer into it
+func (er *EventRecorder) start(timestamp time.Time, img gocv.Mat) error {
+	name := er.clipPrefix() + timestamp.Format(eventTimeLayout)
+	er.basePath = filepath.Join(er.settings.Directory, name)
+	videoPath := er.basePath + ".avi"
+
+	writer, err := gocv.VideoWriterFile(videoPath, er.settings.Codec, er.settings.FPS, img.Cols(), img.Rows(), true)
+	if err != nil {
+		return errors.Wrapf(err, "Can't open video writer for %s", videoPath)
+	}
+	er.writer = writer
+	er.lastSeen = timestamp
+
+	startedAt := timestamp
+	if len(er.preRoll) != 0 {
+		startedAt = er.preRoll[0].timestamp
+	}
+	er.sidecar = &eventSidecar{
+		Stream:    er.streamName,
+		Video:     filepath.Base(videoPath),
+		StartedAt: startedAt,
+		Width:     img.Cols(),
+		Height:    img.Rows(),
+	}
+	er.logger.Info("Event recording has been started", "file", videoPath)
+
+	for _, buffered := range er.preRoll {
+		m, err := gocv.IMDecode(buffered.data, gocv.IMReadColor)
+		if err != nil {
+			er.logger.Warn("Can't decode pre-roll frame", "error", err)
+			continue
+		}
+		if m.Cols() == img.Cols() && m.Rows() == img.Rows() {
+			err = er.writer.Write(m)
+		}
+		m.Close()
+		if err != nil {
+			return errors.Wrap(err, "Can't write pre-roll frame to event clip")
+		}
+	}
+	er.preRoll = er.preRoll[:0]
+	return nil
+}
+
+// finish Closes current clip, writes its sidecar and applies retention policy
+func (er *EventRecorder) finish() error {
+	err := er.writer.Close()
+	er.writer = nil
+	if err != nil {
+		return errors.Wrap(err, "Can't close event clip")
+	}
+
+	data, err := json.MarshalIndent(er.sidecar, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal event sidecar")
+	}
+	if err := os.WriteFile(er.basePath+".json", data, 0o644); err != nil {
+		return errors.Wrap(err, "Can't write event sidecar")
+	}
+	er.logger.Info("Event recording has been finished", "file", er.sidecar.Video, "duration", er.sidecar.EndedAt.Sub(er.sidecar.StartedAt))
+	er.sidecar = nil
+
+	return er.applyRetention(filepath.Base(er.basePath))
+}
+
+// clipPrefix returns prefix of names of clips of the stream
+func (er *EventRecorder) clipPrefix() string {
+	return fmt.Sprintf("%s%s_", eventFilePrefix, sanitizeFileName(er.streamName))
+}
+
+// applyRetention Removes oldest clips of the stream until their total size fits into configured limit.
+// Directory may be shared by several streams: clips of other streams are neither counted nor removed. Clip 'keep' is never removed
+func (er *EventRecorder) applyRetention(keep string) error {
+	if er.settings.MaxSizeMB <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(er.settings.Directory)
+	if err != nil {
+		return errors.Wrap(err, "Can't list events directory")
+	}
+
+	type clip struct {
+		base string
+		size int64
+	}
+	prefix := er.clipPrefix()
+	clips := make(map[string]*clip)
+	var total int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		// Prefix of stream 'cam' matches clips of stream 'cam_2' too
+		if _, err := time.Parse(eventTimeLayout, strings.TrimPrefix(base, prefix)); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if clips[base] == nil {
+			clips[base] = &clip{base: base}
+		}
+		clips[base].size += info.Size()
+		total += info.Size()
+	}
+
+	// Names contain timestamp, so lexical order is chronological
+	ordered := make([]*clip, 0, len(clips))
+	for _, c := range clips {
+		ordered = append(ordered, c)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].base < ordered[j].base })
+
+	limit := int64(er.settings.MaxSizeMB) * 1024 * 1024
+	for _, c := range ordered {
+		if total <= limit {
+			break
+		}
+		if c.base == keep {
+			continue
+		}
+		for _, ext := range []string{".avi", ".json"} {
+			_ = os.Remove(filepath.Join(er.settings.Directory, c.base+ext))
+		}
+		total -= c.size
+		er.logger.Info("Event clip has been removed by retention policy", "clip", c.base)
+	}
+	return nil
+}
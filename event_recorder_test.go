@@ -0,0 +1,68 @@
+package ml
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventRecorderRetention(t *testing.T) {
+	const mb = 1024 * 1024
+	tests := []struct {
+		maxSizeMB int
+		// Clips of stream 'cam' left after retention
+		expected []string
+	}{
+		{4, []string{"event_cam_20261019T120000.000", "event_cam_20261019T120100.000", "event_cam_20261019T120200.000"}},
+		{2, []string{"event_cam_20261019T120200.000"}},
+		// Just finished clip is kept even if it doesn't fit into limit
+		{1, []string{"event_cam_20261019T120200.000"}},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		files := map[string]int64{
+			"event_cam_20261019T120000.000.avi":   mb,
+			"event_cam_20261019T120000.000.json":  100,
+			"event_cam_20261019T120100.000.avi":   mb,
+			"event_cam_20261019T120200.000.avi":   3 * mb / 2,
+			"event_cam_20261019T120200.000.json":  100,
+			"event_cam_2_20261019T115900.000.avi": 2 * mb,
+			"event_door_20261019T115800.000.avi":  2 * mb,
+		}
+		for name, size := range files {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Truncate(path, size); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		settings := &EventRecorderSettings{Directory: dir, MaxSizeMB: test.maxSizeMB}
+		er, err := NewEventRecorder(settings, "cam", slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := er.applyRetention("event_cam_20261019T120200.000"); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]bool{}
+		for _, base := range test.expected {
+			expected[base] = true
+		}
+		for name := range files {
+			_, err := os.Stat(filepath.Join(dir, name))
+			exists := err == nil
+			base := name[:len(name)-len(filepath.Ext(name))]
+			// Clips of other streams sharing directory are never removed
+			keep := expected[base] || base == "event_cam_2_20261019T115900.000" || base == "event_door_20261019T115800.000"
+			if exists != keep {
+				t.Errorf("limit %d MB: file %s exists %v, expected %v", test.maxSizeMB, name, exists, keep)
+			}
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package ml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var eventsBucket = []byte("events")
+
+// StoredEvent Event persisted in EventStore
+type StoredEvent struct {
+	ID string `json:"id"`
+	Event
+}
+
+// EventQuery Filter of EventStore.Query. Zero values match everything
+type EventQuery struct {
+	From   time.Time
+	To     time.Time
+	Stream string
+	Class  string
+	Zone   string
+	Type   EventType
+	// Return newest events first
+	Desc bool
+	// Identifier of the last event of previous page
+	Cursor string
+	Limit  int
+}
+
+// EventStore Embedded persistent storage of events with query API and retention policy.
+// Keys are ordered by time of event, so time range queries are cursor seeks
+type EventStore struct {
+	settings *EventStoreSettings
+	db       *bolt.DB
+	logger   *slog.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewEventStore Opens (or creates) database and starts retention in separate goroutine
+func NewEventStore(settings *EventStoreSettings, logger *slog.Logger) (*EventStore, error) {
+	db, err := bolt.Open(settings.Path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't open event store %s", settings.Path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "Can't create events bucket")
+	}
+
+	store := &EventStore{
+		settings: settings,
+		db:       db,
+		logger:   logger.With("component", "event_store"),
+		stop:     make(chan struct{}),
+	}
+	if settings.RetentionDays > 0 {
+		store.wg.Add(1)
+		go store.pruneLoop()
+	}
+	return store, nil
+}
+
+// HandleEvents implements EventHandler
+func (es *EventStore) HandleEvents(batch *EventBatch) error {
+	var events []*Event
+	for _, event := range batch.Events {
+		if stringInSlice((*string)(&event.Type), es.settings.Events) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return es.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket)
+		for _, event := range events {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(eventKey(event.Timestamp, seq), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close implements EventHandler
+func (es *EventStore) Close() error {
+	close(es.stop)
+	es.wg.Wait()
+	return es.db.Close()
+}
+
+// Query returns events matching query and cursor of the next page (empty if there are no more events)
+func (es *EventStore) Query(q EventQuery) ([]*StoredEvent, string, error) {
+	if q.Limit <= 0 || q.Limit > es.settings.MaxPageSize {
+		q.Limit = es.settings.MaxPageSize
+	}
+	var after []byte
+	if q.Cursor != "" {
+		var err error
+		if after, err = hex.DecodeString(q.Cursor); err != nil {
+			return nil, "", errors.Wrap(err, "Invalid cursor")
+		}
+	}
+
+	var result []*StoredEvent
+	next := ""
+	err := es.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(eventsBucket).Cursor()
+		var k, v []byte
+		step := c.Next
+		switch {
+		case q.Desc:
+			step = c.Prev
+			if after != nil {
+				k, v = c.Seek(after)
+				if k != nil {
+					k, v = c.Prev()
+				} else {
+					k, v = c.Last()
+				}
+			} else if !q.To.IsZero() {
+				k, v = c.Seek(eventKey(q.To, 0))
+				if k == nil {
+					k, v = c.Last()
+				}
+			} else {
+				k, v = c.Last()
+			}
+		case after != nil:
+			if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		case !q.From.IsZero():
+			k, v = c.Seek(eventKey(q.From, 0))
+		default:
+			k, v = c.First()
+		}
+
+		for ; k != nil; k, v = step() {
+			timestamp := keyTime(k)
+			if !q.From.IsZero() && timestamp.Before(q.From) {
+				if q.Desc {
+					break
+				}
+				continue
+			}
+			if !q.To.IsZero() && !timestamp.Before(q.To) {
+				if q.Desc {
+					continue
+				}
+				break
+			}
+			event := &StoredEvent{ID: hex.EncodeToString(k)}
+			if err := json.Unmarshal(v, &event.Event); err != nil {
+				return err
+			}
+			if !q.match(&event.Event) {
+				continue
+			}
+			if len(result) == q.Limit {
+				next = result[len(result)-1].ID
+				break
+			}
+			result = append(result, event)
+		}
+		return nil
+	})
+	return result, next, err
+}
+
+// Get returns event by identifier
+func (es *EventStore) Get(id string) (*StoredEvent, error) {
+	key, err := hex.DecodeString(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid event identifier")
+	}
+	var event *StoredEvent
+	err = es.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(eventsBucket).Get(key)
+		if v == nil {
+			return nil
+		}
+		event = &StoredEvent{ID: id}
+		return json.Unmarshal(v, &event.Event)
+	})
+	return event, err
+}
+
+// Prune Removes events older than provided time together with their thumbnails and full-frame snapshots
+func (es *EventStore) Prune(before time.Time) (int, error) {
+	var files []string
+	removed := 0
+	err := es.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(eventsBucket).Cursor()
+		limit := eventKey(before, 0)
+		for k, v := c.First(); k != nil && bytes.Compare(k, limit) < 0; k, v = c.Next() {
+			var event Event
+			if err := json.Unmarshal(v, &event); err == nil {
+				for _, path := range []string{event.Thumbnail, event.Frame} {
+					if path != "" {
+						files = append(files, path)
+					}
+				}
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			es.logger.Warn("Can't remove snapshot of event", "file", path, "error", err)
+		}
+	}
+	return removed, nil
+}
+
+// RegisterRoutes Registers query endpoints on router
+func (es *EventStore) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/events", es.serveQuery).Methods(http.MethodGet)
+	router.HandleFunc("/api/events/{id:[0-9a-f]+}", es.serveEvent).Methods(http.MethodGet)
+	router.HandleFunc("/api/events/{id:[0-9a-f]+}/thumbnail", es.serveThumbnail).Methods(http.MethodGet)
+}
+
+func (es *EventStore) pruneLoop() {
+	defer es.wg.Done()
+	ticker := time.NewTicker(time.Duration(es.settings.PruneIntervalSec * float64(time.Second)))
+	defer ticker.Stop()
+	for {
+		before := time.Now().Add(-time.Duration(es.settings.RetentionDays * 24 * float64(time.Hour)))
+		if removed, err := es.Prune(before); err != nil {
+			es.logger.Error("Can't prune events", "error", err)
+		} else if removed != 0 {
+			es.logger.Info("Events have been removed by retention policy", "removed", removed)
+		}
+		select {
+		case <-ticker.C:
+		case <-es.stop:
+			return
+		}
+	}
+}
+
+func (es *EventStore) serveQuery(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := EventQuery{
+		Stream: params.Get("stream"),
+		Class:  params.Get("class"),
+		Zone:   params.Get("zone"),
+		Type:   EventType(params.Get("type")),
+		Desc:   params.Get("order") == "desc",
+		Cursor: params.Get("cursor"),
+	}
+	var err error
+	if v := params.Get("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid 'from': "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid 'to': "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid 'limit': "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, next, err := es.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if events == nil {
+		events = []*StoredEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Events     []*StoredEvent `json:"events"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}{events, next})
+}
+
+func (es *EventStore) serveEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := es.lookup(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(event)
+}
+
+func (es *EventStore) serveThumbnail(w http.ResponseWriter, r *http.Request) {
+	event, ok := es.lookup(w, r)
+	if !ok {
+		return
+	}
+	if event.Thumbnail == "" {
+		http.Error(w, "event has no thumbnail", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, event.Thumbnail)
+}
+
+func (es *EventStore) lookup(w http.ResponseWriter, r *http.Request) (*StoredEvent, bool) {
+	event, err := es.Get(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if event == nil {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return nil, false
+	}
+	return event, true
+}
+
+func (q *EventQuery) match(event *Event) bool {
+	if q.Stream != "" && event.Stream != q.Stream {
+		return false
+	}
+	if q.Type != "" && event.Type != q.Type {
+		return false
+	}
+	if q.Class != "" && (event.Object == nil || event.Object.ClassName != q.Class) {
+		return false
+	}
+	if q.Zone != "" && !stringInSlice(&q.Zone, event.Zones) {
+		return false
+	}
+	return true
+}
+
+// eventKey Builds key ordered by time: big-endian unix nanoseconds followed by sequence number
+func eventKey(timestamp time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(timestamp.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
@@ -0,0 +1,72 @@
+package ml
+
+import (
+	"image"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventStorePruneRemovesSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	settings := &EventStoreSettings{Path: filepath.Join(dir, "events.db")}
+	settings.Prepare("cam")
+	store, err := NewEventStore(settings, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	var events []*Event
+	var files []string
+	for i, name := range []string{"old", "new"} {
+		crop := filepath.Join(dir, name+"_crop.jpg")
+		frame := filepath.Join(dir, name+"_frame.jpg")
+		for _, path := range []string{crop, frame} {
+			if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		files = append(files, crop, frame)
+		events = append(events, &Event{
+			Type:      EventTrackNew,
+			Stream:    "cam",
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Object:    &DetectedObject{Rect: image.Rect(0, 0, 10, 10), ClassName: "person", TrackID: i + 1},
+			Thumbnail: crop,
+			Frame:     frame,
+		})
+	}
+	if err := store.HandleEvents(&EventBatch{Stream: "cam", Timestamp: start, Events: events}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := store.Prune(start.Add(30 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed event, got %d", removed)
+	}
+	for i, path := range files {
+		_, err := os.Stat(path)
+		if i < 2 && !os.IsNotExist(err) {
+			t.Errorf("snapshot %s of pruned event still exists", path)
+		}
+		if i >= 2 && err != nil {
+			t.Errorf("snapshot %s of kept event has been removed: %v", path, err)
+		}
+	}
+}
+
+func TestEventStoreSettingsDefaultPath(t *testing.T) {
+	settings := &EventStoreSettings{}
+	settings.Prepare("rtsp://cam/1")
+	if settings.Path != "events_rtsp___cam_1.db" {
+		t.Errorf("unexpected default path %s", settings.Path)
+	}
+}
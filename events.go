@@ -0,0 +1,106 @@
+package ml
+
+import (
+	"image"
+	"time"
+)
+
+// EventType Kind of event emitted by pipeline
+type EventType string
+
+const (
+	// EventDetection Object has been detected on frame
+	EventDetection EventType = "detection"
+	// EventTrackNew Object has appeared and new track has been started
+	EventTrackNew EventType = "track_new"
+	// EventTrackLost Track hasn't been matched with detections for too long
+	EventTrackLost EventType = "track_lost"
+	// EventFall Tracked person has fallen (pose models only)
+	EventFall EventType = "fall"
+)
+
+// Event Detection or track event
+type Event struct {
+	Type      EventType       `json:"type"`
+	Stream    string          `json:"stream"`
+	Timestamp time.Time       `json:"timestamp"`
+	Object    *DetectedObject `json:"object"`
+	Zones     []string        `json:"zones,omitempty"`
+	// Time of the first detection of track (track events only)
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	// Path to saved snapshot of object (if any)
+	Thumbnail string `json:"thumbnail,omitempty"`
+	// Path to saved full frame with object (if any)
+	Frame string `json:"frame,omitempty"`
+}
+
+// EventBatch Events emitted while processing single frame
+type EventBatch struct {
+	Stream    string
+	Timestamp time.Time
+	Events    []*Event
+	// Number of alive tracks per zone
+	Occupancy map[string]int
+}
+
+// EventHandler Consumer of events
+type EventHandler interface {
+	HandleEvents(batch *EventBatch) error
+	Close() error
+}
+
+// newEventBatch Builds events for detections and tracker update of single frame
+func newEventBatch(stream string, timestamp time.Time, detected []*DetectedObject, update TrackerUpdate, tracks []*Track, zones []*Zone, frameSize image.Point) *EventBatch {
+	batch := &EventBatch{
+		Stream:    stream,
+		Timestamp: timestamp,
+		Events:    make([]*Event, 0, len(detected)+len(update.New)+len(update.Lost)),
+		Occupancy: make(map[string]int, len(zones)),
+	}
+	for _, detection := range detected {
+		batch.Events = append(batch.Events, newObjectEvent(EventDetection, stream, timestamp, detection, zones, frameSize))
+	}
+	for _, track := range update.New {
+		batch.Events = append(batch.Events, newTrackEvent(EventTrackNew, stream, timestamp, track, zones, frameSize))
+	}
+	for _, track := range update.Lost {
+		batch.Events = append(batch.Events, newTrackEvent(EventTrackLost, stream, timestamp, track, zones, frameSize))
+	}
+	for _, zone := range zones {
+		batch.Occupancy[zone.Name] = 0
+	}
+	for _, track := range tracks {
+		for _, name := range zoneNames(zones, track.Rect, frameSize) {
+			batch.Occupancy[name]++
+		}
+	}
+	return batch
+}
+
+func newObjectEvent(eventType EventType, stream string, timestamp time.Time, object *DetectedObject, zones []*Zone, frameSize image.Point) *Event {
+	return &Event{
+		Type:      eventType,
+		Stream:    stream,
+		Timestamp: timestamp,
+		Object:    object,
+		Zones:     zoneNames(zones, object.Rect, frameSize),
+	}
+}
+
+func newTrackEvent(eventType EventType, stream string, timestamp time.Time, track *Track, zones []*Zone, frameSize image.Point) *Event {
+	firstSeen := track.FirstSeen
+	return &Event{
+		Type:      eventType,
+		Stream:    stream,
+		Timestamp: timestamp,
+		Object: &DetectedObject{
+			Rect:       track.Rect,
+			ClassID:    track.ClassID,
+			ClassName:  track.ClassName,
+			Confidence: track.MaxConfidence,
+			TrackID:    track.ID,
+		},
+		Zones:     zoneNames(zones, track.Rect, frameSize),
+		FirstSeen: &firstSeen,
+	}
+}
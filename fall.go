@@ -0,0 +1,117 @@
+package ml
+
+import (
+	"image"
+	"math"
+	"time"
+)
+
+// States of tracks which haven't been seen for this time are removed
+const fallStateTTL = 10 * time.Second
+
+// fallState Fall detection state of single track
+type fallState struct {
+	lastSeen    time.Time
+	torsoY      float64
+	fastAt      time.Time
+	lyingSince  time.Time
+	fired       bool
+	lastFiredAt time.Time
+}
+
+// FallDetector Detects falls of tracked persons by angle of torso and preceding downward movement
+type FallDetector struct {
+	settings *FallSettings
+	states   map[int]*fallState
+}
+
+// NewFallDetector Creates FallDetector. Settings have to be prepared
+func NewFallDetector(settings *FallSettings) *FallDetector {
+	return &FallDetector{
+		settings: settings,
+		states:   make(map[int]*fallState),
+	}
+}
+
+// Update Feeds detections of frame and returns persons which have just fallen. Detections must be tracked
+func (fd *FallDetector) Update(detected []*DetectedObject, timestamp time.Time, frameSize image.Point) []*DetectedObject {
+	var fallen []*DetectedObject
+	for _, detection := range detected {
+		if detection.TrackID == 0 || len(detection.Keypoints) != poseKeypoints {
+			continue
+		}
+		top, bottom, ok := fd.torso(detection.Keypoints)
+		if !ok {
+			continue
+		}
+		state, found := fd.states[detection.TrackID]
+		if !found {
+			state = &fallState{}
+			fd.states[detection.TrackID] = state
+		}
+
+		// Downward speed of torso center in frame heights per second
+		centerY := (top.Y + bottom.Y) / 2 / float64(frameSize.Y)
+		if found {
+			if dt := timestamp.Sub(state.lastSeen).Seconds(); dt > 0 && (centerY-state.torsoY)/dt >= fd.settings.MinSpeed {
+				state.fastAt = timestamp
+			}
+		}
+		state.torsoY = centerY
+		state.lastSeen = timestamp
+
+		angle := math.Atan2(math.Abs(bottom.X-top.X), math.Abs(bottom.Y-top.Y)) * 180 / math.Pi
+		if angle <= fd.settings.MaxTorsoAngleDeg {
+			state.lyingSince = time.Time{}
+			state.fired = false
+			continue
+		}
+		if state.lyingSince.IsZero() {
+			state.lyingSince = timestamp
+		}
+		if state.fired || timestamp.Sub(state.lyingSince).Seconds() < fd.settings.MinDurationSec {
+			continue
+		}
+		if fd.settings.MinSpeed > 0 && (state.fastAt.IsZero() || state.lyingSince.Sub(state.fastAt).Seconds() > fd.settings.SpeedWindowSec) {
+			continue
+		}
+		if !state.lastFiredAt.IsZero() && timestamp.Sub(state.lastFiredAt).Seconds() < fd.settings.CooldownSec {
+			continue
+		}
+		state.fired = true
+		state.lastFiredAt = timestamp
+		fallen = append(fallen, detection)
+	}
+
+	for id, state := range fd.states {
+		if timestamp.Sub(state.lastSeen) > fallStateTTL {
+			delete(fd.states, id)
+		}
+	}
+	return fallen
+}
+
+// torso returns middle points of shoulders and hips
+func (fd *FallDetector) torso(keypoints []Keypoint) (top, bottom vec2, ok bool) {
+	top, okTop := fd.middle(keypoints[KeypointLeftShoulder], keypoints[KeypointRightShoulder])
+	bottom, okBottom := fd.middle(keypoints[KeypointLeftHip], keypoints[KeypointRightHip])
+	return top, bottom, okTop && okBottom
+}
+
+// middle returns middle point of visible keypoints of pair
+func (fd *FallDetector) middle(a, b Keypoint) (vec2, bool) {
+	threshold := float32(fd.settings.KeypointThreshold)
+	switch {
+	case a.Confidence >= threshold && b.Confidence >= threshold:
+		return vec2{X: float64(a.Point.X+b.Point.X) / 2, Y: float64(a.Point.Y+b.Point.Y) / 2}, true
+	case a.Confidence >= threshold:
+		return vec2{X: float64(a.Point.X), Y: float64(a.Point.Y)}, true
+	case b.Confidence >= threshold:
+		return vec2{X: float64(b.Point.X), Y: float64(b.Point.Y)}, true
+	}
+	return vec2{}, false
+}
+
+type vec2 struct {
+	X, Y float64
+}
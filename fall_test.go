@@ -0,0 +1,114 @@
+package ml
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+// fallPose Person whose torso center is at provided height. Torso is vertical or horizontal when lying
+type fallPose struct {
+	centerY int
+	lying   bool
+}
+
+func (p fallPose) detection() *DetectedObject {
+	keypoints := make([]Keypoint, poseKeypoints)
+	top, bottom := image.Pt(320, p.centerY-30), image.Pt(320, p.centerY+30)
+	if p.lying {
+		top, bottom = image.Pt(290, p.centerY), image.Pt(350, p.centerY)
+	}
+	for _, i := range []int{KeypointLeftShoulder, KeypointRightShoulder} {
+		keypoints[i] = Keypoint{Point: top, Confidence: 0.9}
+	}
+	for _, i := range []int{KeypointLeftHip, KeypointRightHip} {
+		keypoints[i] = Keypoint{Point: bottom, Confidence: 0.9}
+	}
+	return &DetectedObject{ClassName: "person", TrackID: 1, Keypoints: keypoints}
+}
+
+// repeatPose returns n frames of the same pose
+func repeatPose(n int, pose fallPose) []fallPose {
+	poses := make([]fallPose, n)
+	for i := range poses {
+		poses[i] = pose
+	}
+	return poses
+}
+
+// movePose returns n frames moving torso center linearly from one height to another
+func movePose(n int, from, to int, lying bool) []fallPose {
+	poses := make([]fallPose, n)
+	for i := range poses {
+		poses[i] = fallPose{centerY: from + (to-from)*(i+1)/n, lying: lying}
+	}
+	return poses
+}
+
+func TestFallDetectorUpdate(t *testing.T) {
+	// 10 frames per second, frame height 480
+	tests := []struct {
+		name  string
+		poses [][]fallPose
+		// Index of frame emitting fall event, -1 means no event
+		expected int
+	}{
+		{
+			name: "fall",
+			poses: [][]fallPose{
+				repeatPose(10, fallPose{centerY: 200}),
+				// 0.3 frame heights within 0.3 s
+				movePose(3, 200, 344, false),
+				repeatPose(20, fallPose{centerY: 400, lying: true}),
+			},
+			// Lying since frame 13, event after 1 s
+			expected: 23,
+		},
+		{
+			name: "lying down slowly",
+			poses: [][]fallPose{
+				repeatPose(10, fallPose{centerY: 200}),
+				// 0.42 frame heights within 4 s
+				movePose(40, 200, 400, false),
+				repeatPose(30, fallPose{centerY: 400, lying: true}),
+			},
+			expected: -1,
+		},
+		{
+			name: "bending over fast",
+			poses: [][]fallPose{
+				repeatPose(10, fallPose{centerY: 200}),
+				movePose(3, 200, 344, false),
+				repeatPose(5, fallPose{centerY: 344, lying: true}),
+				repeatPose(20, fallPose{centerY: 200}),
+			},
+			expected: -1,
+		},
+	}
+
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	frameSize := image.Pt(640, 480)
+	for _, test := range tests {
+		settings := &FallSettings{Enable: true}
+		settings.Prepare()
+		fd := NewFallDetector(settings)
+
+		var poses []fallPose
+		for _, part := range test.poses {
+			poses = append(poses, part...)
+		}
+		var events []int
+		for i, pose := range poses {
+			timestamp := start.Add(time.Duration(i) * 100 * time.Millisecond)
+			if fallen := fd.Update([]*DetectedObject{pose.detection()}, timestamp, frameSize); len(fallen) != 0 {
+				events = append(events, i)
+			}
+		}
+		switch {
+		case test.expected < 0 && len(events) != 0:
+			t.Errorf("%s: unexpected fall events at frames %v", test.name, events)
+		case test.expected >= 0 && (len(events) != 1 || events[0] != test.expected):
+			t.Errorf("%s: expected single fall event at frame %d, got %v", test.name, test.expected, events)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ml
 
 import (
 	"image"
+	"time"
 
 	"gocv.io/x/gocv"
 )
@@ -11,13 +12,22 @@ type FrameData struct {
 	ImgSource     gocv.Mat //  Source image
 	ImgScaled     gocv.Mat // Scaled image
 	ImgScaledCopy gocv.Mat // Copy of scaled image
+	ImgUnredacted gocv.Mat // Annotated scaled image before redaction. Empty when redaction is disabled
+
+	ROI image.Rectangle // Region of scaled image copied to ImgScaledCopy
+
+	Inferred  bool // Objects of frame come from inference on this frame, not predicted by tracker or reused from previous frame
+	Predicted bool // Objects of frame are predicted by tracker or reused from previous successful inference
+
+	Timestamp time.Time // Time when source image has been grabbed
 }
 
 // NewFrameData Simplifies creation of FrameData
 func NewFrameData() *FrameData {
 	fd := FrameData{
-		ImgSource: gocv.NewMat(),
-		ImgScaled: gocv.NewMat(),
+		ImgSource:     gocv.NewMat(),
+		ImgScaled:     gocv.NewMat(),
+		ImgUnredacted: gocv.NewMat(),
 	}
 	return &fd
 }
@@ -27,11 +37,17 @@ func (fd *FrameData) Close() {
 	_ = fd.ImgSource.Close()
 	_ = fd.ImgScaled.Close()
 	_ = fd.ImgScaledCopy.Close()
+	_ = fd.ImgUnredacted.Close()
 }
 
-// Preprocess Scales image to given width and height
-func (fd *FrameData) Preprocess(width, height int) error {
+// Preprocess Scales image to given width and height. When region of interest is provided, only its part is copied for detection
+func (fd *FrameData) Preprocess(width, height int, roi *ROI) error {
 	gocv.Resize(fd.ImgSource, &fd.ImgScaled, image.Point{X: width, Y: height}, 0, 0, gocv.InterpolationDefault)
+	if roi != nil {
+		fd.ImgScaledCopy, fd.ROI = roi.Crop(fd.ImgScaled)
+		return nil
+	}
 	fd.ImgScaledCopy = fd.ImgScaled.Clone()
+	fd.ROI = image.Rect(0, 0, width, height)
 	return nil
 }
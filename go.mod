@@ -1,18 +1,25 @@
 module github.com/genert/ml
 
-go 1.18
+go 1.21
 
 require (
+	github.com/ailumiyana/goav-incr v0.1.0
+	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/gorilla/mux v1.8.0
 	github.com/mattn/go-mjpeg v0.0.3
 	github.com/mike1808/h264decoder v0.0.1
+	github.com/mochi-mqtt/server/v2 v2.4.6
 	github.com/pkg/errors v0.9.1
 	github.com/projecthunt/reuseable v0.0.7
 	github.com/rs/cors v1.8.2
+	go.etcd.io/bbolt v1.3.8
 	gocv.io/x/gocv v0.30.0
 )
 
 require (
-	github.com/ailumiyana/goav-incr v0.1.0 // indirect
-	golang.org/x/sys v0.0.0-20211103235746-7861aae1554b // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/rs/xid v1.4.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
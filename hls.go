@@ -0,0 +1,247 @@
+package ml
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	hlsPlaylistName = "stream.m3u8"
+	// Pattern of segment files written by ffmpeg
+	hlsSegmentPattern = "segment_*.ts"
+	// Number of raw frames waiting for encoder. Frames are dropped when encoder is too slow
+	hlsQueueSize = 4
+)
+
+// HLSPlaylist Parsed HLS media playlist
+type HLSPlaylist struct {
+	TargetDuration int          `json:"target_duration"`
+	MediaSequence  int          `json:"media_sequence"`
+	Segments       []HLSSegment `json:"segments"`
+	Ended          bool         `json:"ended"`
+}
+
+// HLSSegment Segment of HLS media playlist
+type HLSSegment struct {
+	URI      string  `json:"uri"`
+	Duration float64 `json:"duration"`
+}
+
+// ParseHLSPlaylist Parses HLS media playlist
+func ParseHLSPlaylist(r io.Reader) (*HLSPlaylist, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		return nil, fmt.Errorf("missing #EXTM3U header")
+	}
+	playlist := &HLSPlaylist{}
+	duration := -1.0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var err error
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			playlist.TargetDuration, err = strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"))
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			playlist.MediaSequence, err = strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0]
+			duration, err = strconv.ParseFloat(value, 64)
+		case line == "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		case strings.HasPrefix(line, "#"):
+		default:
+			if duration < 0 {
+				return nil, fmt.Errorf("segment %s without #EXTINF", line)
+			}
+			playlist.Segments = append(playlist.Segments, HLSSegment{URI: line, Duration: duration})
+			duration = -1
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid line '%s'", line)
+		}
+	}
+	return playlist, scanner.Err()
+}
+
+// HLSStreamer Encodes annotated frames to HLS segments with rolling playlist via ffmpeg
+type HLSStreamer struct {
+	settings *HLSSettings
+	logger   *slog.Logger
+
+	cmd    *exec.Cmd
+	frames chan []byte
+	done   chan error
+	width  int
+	height int
+}
+
+// NewHLSStreamer Creates HLSStreamer. Encoder is started on the first frame, when frame size is known
+func NewHLSStreamer(settings *HLSSettings, logger *slog.Logger) (*HLSStreamer, error) {
+	if err := os.MkdirAll(settings.Directory, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "Can't create HLS directory %s", settings.Directory)
+	}
+	if err := cleanHLSDirectory(settings.Directory); err != nil {
+		return nil, errors.Wrapf(err, "Can't clean HLS directory %s", settings.Directory)
+	}
+	if _, err := exec.LookPath(settings.FFmpegPath); err != nil {
+		return nil, errors.Wrapf(err, "Can't find ffmpeg")
+	}
+	return &HLSStreamer{
+		settings: settings,
+		logger:   logger.With("component", "hls"),
+	}, nil
+}
+
+// cleanHLSDirectory Removes stale playlist and segments of previous run. Other files are kept
+func cleanHLSDirectory(dir string) error {
+	stale, err := filepath.Glob(filepath.Join(dir, hlsSegmentPattern))
+	if err != nil {
+		return err
+	}
+	stale = append(stale, filepath.Join(dir, hlsPlaylistName), filepath.Join(dir, hlsPlaylistName+".tmp"))
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterRoutes Registers playlist, segments and playlist status endpoints on router
+func (hs *HLSStreamer) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/hls/status", hs.serveStatus).Methods(http.MethodGet)
+	files := http.StripPrefix("/hls/", http.FileServer(http.Dir(hs.settings.Directory)))
+	router.PathPrefix("/hls/").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".m3u8") {
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		files.ServeHTTP(w, r)
+	})).Methods(http.MethodGet)
+}
+
+// Playlist Reads and parses current playlist
+func (hs *HLSStreamer) Playlist() (*HLSPlaylist, error) {
+	f, err := os.Open(filepath.Join(hs.settings.Directory, hlsPlaylistName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHLSPlaylist(f)
+}
+
+// Consume implements FrameSink
+func (hs *HLSStreamer) Consume(frame *FrameData, _ []*DetectedObject) error {
+	img := frame.ImgScaled
+	if img.Empty() {
+		return nil
+	}
+	if hs.cmd == nil {
+		if err := hs.start(img.Cols(), img.Rows()); err != nil {
+			return err
+		}
+	}
+	if img.Cols() != hs.width || img.Rows() != hs.height {
+		return fmt.Errorf("frame size %dx%d differs from HLS stream size %dx%d", img.Cols(), img.Rows(), hs.width, hs.height)
+	}
+
+	select {
+	case err := <-hs.done:
+		hs.cmd = nil
+		return errors.Wrap(err, "HLS encoder has been stopped")
+	case hs.frames <- img.ToBytes():
+	default:
+		hs.logger.Debug("HLS encoder is busy. Frame has been dropped")
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (hs *HLSStreamer) Close() error {
+	if hs.cmd == nil {
+		return nil
+	}
+	close(hs.frames)
+	err := <-hs.done
+	hs.cmd = nil
+	return err
+}
+
+// hlsArgs Arguments of ffmpeg encoding raw BGR frames from stdin to HLS segments with rolling playlist
+func hlsArgs(settings *HLSSettings, width, height int) []string {
+	gop := int(math.Round(settings.FPS * settings.SegmentDurationSec))
+	bitrate := fmt.Sprintf("%dk", settings.BitrateKbps)
+	return []string{
+		"-hide_banner", "-loglevel", "error",
+		"-f", "rawvideo", "-pix_fmt", "bgr24", "-s", fmt.Sprintf("%dx%d", width, height),
+		"-use_wallclock_as_timestamps", "1", "-i", "-",
+		"-an", "-c:v", "libx264", "-preset", settings.Preset, "-tune", "zerolatency", "-pix_fmt", "yuv420p",
+		"-b:v", bitrate, "-maxrate", bitrate, "-bufsize", fmt.Sprintf("%dk", 2*settings.BitrateKbps),
+		"-r", strconv.FormatFloat(settings.FPS, 'f', -1, 64), "-g", strconv.Itoa(gop), "-keyint_min", strconv.Itoa(gop), "-sc_threshold", "0",
+		"-f", "hls",
+		"-hls_time", strconv.FormatFloat(settings.SegmentDurationSec, 'f', -1, 64),
+		"-hls_list_size", strconv.Itoa(settings.PlaylistSize),
+		"-hls_flags", "delete_segments+omit_endlist",
+		"-hls_segment_filename", filepath.Join(settings.Directory, "segment_%06d.ts"),
+		filepath.Join(settings.Directory, hlsPlaylistName),
+	}
+}
+
+func (hs *HLSStreamer) start(width, height int) error {
+	cmd := exec.Command(hs.settings.FFmpegPath, hlsArgs(hs.settings, width, height)...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "Can't open ffmpeg stdin")
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "Can't start ffmpeg")
+	}
+	hs.logger.Info("HLS encoder has been started", "width", width, "height", height, "bitrate_kbps", hs.settings.BitrateKbps)
+
+	hs.cmd = cmd
+	hs.width = width
+	hs.height = height
+	hs.frames = make(chan []byte, hlsQueueSize)
+	hs.done = make(chan error, 1)
+	go func(frames <-chan []byte) {
+		var writeErr error
+		for data := range frames {
+			if _, writeErr = stdin.Write(data); writeErr != nil {
+				hs.logger.Error("Can't write frame to ffmpeg", "error", writeErr)
+				break
+			}
+		}
+		_ = stdin.Close()
+		waitErr := cmd.Wait()
+		if writeErr == nil {
+			writeErr = waitErr
+		}
+		hs.done <- writeErr
+	}(hs.frames)
+	return nil
+}
+
+func (hs *HLSStreamer) serveStatus(w http.ResponseWriter, _ *http.Request) {
+	playlist, err := hs.Playlist()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(playlist)
+}
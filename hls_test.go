@@ -0,0 +1,121 @@
+package ml
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHLSPlaylist(t *testing.T) {
+	content := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:7
+#EXTINF:2.000000,
+segment_000007.ts
+#EXTINF:1.966667,
+segment_000008.ts
+`
+	playlist, err := ParseHLSPlaylist(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.TargetDuration != 2 || playlist.MediaSequence != 7 || playlist.Ended {
+		t.Fatalf("unexpected playlist header: %+v", playlist)
+	}
+	expected := []HLSSegment{{URI: "segment_000007.ts", Duration: 2}, {URI: "segment_000008.ts", Duration: 1.966667}}
+	if len(playlist.Segments) != len(expected) {
+		t.Fatalf("expected %d segments, got %d", len(expected), len(playlist.Segments))
+	}
+	for i := range expected {
+		if playlist.Segments[i] != expected[i] {
+			t.Errorf("segment %d: expected %+v, got %+v", i, expected[i], playlist.Segments[i])
+		}
+	}
+}
+
+func TestParseHLSPlaylistInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no header":      "#EXT-X-TARGETDURATION:2\n",
+		"no extinf":      "#EXTM3U\nsegment_000001.ts\n",
+		"bad duration":   "#EXTM3U\n#EXTINF:abc,\nsegment_000001.ts\n",
+		"bad target dur": "#EXTM3U\n#EXT-X-TARGETDURATION:x\n",
+	} {
+		if _, err := ParseHLSPlaylist(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestHLSGeneratedPlaylist Encodes blank frames with the same ffmpeg arguments as HLSStreamer and parses resulting playlist
+func TestHLSGeneratedPlaylist(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not available")
+	}
+	settings := &HLSSettings{Directory: t.TempDir(), FFmpegPath: "ffmpeg", SegmentDurationSec: 1}
+	settings.Prepare()
+	width, height := 64, 48
+	cmd := exec.Command(settings.FFmpegPath, hlsArgs(settings, width, height)...)
+	// Wall clock timestamps of piped frames would collapse to zero duration
+	for i, arg := range cmd.Args {
+		if arg == "-use_wallclock_as_timestamps" {
+			cmd.Args = append(cmd.Args[:i], append([]string{"-framerate", "15"}, cmd.Args[i+2:]...)...)
+			break
+		}
+	}
+	frames := 3 * int(settings.FPS)
+	cmd.Stdin = bytes.NewReader(make([]byte, frames*width*height*3))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg failed: %v\n%s", err, output)
+	}
+
+	f, err := os.Open(filepath.Join(settings.Directory, hlsPlaylistName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	playlist, err := ParseHLSPlaylist(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.TargetDuration <= 0 || len(playlist.Segments) == 0 {
+		t.Fatalf("unexpected playlist: %+v", playlist)
+	}
+	total := 0.0
+	for _, segment := range playlist.Segments {
+		if _, err := os.Stat(filepath.Join(settings.Directory, segment.URI)); err != nil {
+			t.Errorf("segment %s: %v", segment.URI, err)
+		}
+		total += segment.Duration
+	}
+	if total < 2 {
+		t.Errorf("expected about 3s of video in playlist, got %.2fs", total)
+	}
+}
+
+func TestCleanHLSDirectoryKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := []string{hlsPlaylistName, "segment_000001.ts", "segment_000002.ts"}
+	kept := []string{"notes.txt", "video.ts", "other.m3u8"}
+	for _, name := range append(stale, kept...) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cleanHLSDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range stale {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s must be removed", name)
+		}
+	}
+	for _, name := range kept {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s must be kept: %v", name, err)
+		}
+	}
+}
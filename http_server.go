@@ -0,0 +1,173 @@
+package ml
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+)
+
+const httpShutdownTimeout = 2 * time.Second
+
+type principalKey struct{}
+
+// Principal Authenticated user or token of HTTP request
+type Principal struct {
+	Name  string
+	Roles []string
+}
+
+// HasRole Checks whether principal has provided role
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && stringInSlice(&role, p.Roles)
+}
+
+// PrincipalFromRequest returns authenticated principal of request (nil if request is not authenticated)
+func PrincipalFromRequest(r *http.Request) *Principal {
+	p, _ := r.Context().Value(principalKey{}).(*Principal)
+	return p
+}
+
+// RequireRole Allows request only for principals with provided role
+func RequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := PrincipalFromRequest(r)
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ml"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !p.HasRole(role) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authMiddleware Authenticates requests by basic credentials or bearer token
+func authMiddleware(settings *AuthSettings, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := authenticate(settings, r); p != nil {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+			return
+		}
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, prefix := range settings.PublicPaths {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="ml"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+	})
+}
+
+func authenticate(settings *AuthSettings, r *http.Request) *Principal {
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, user := range settings.Users {
+			if secureCompare(user.Username, username) && secureCompare(user.Password, password) {
+				return &Principal{Name: user.Username, Roles: user.Roles}
+			}
+		}
+		return nil
+	}
+
+	token := ""
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	} else {
+		// Browsers can't set headers for <img> and <video> sources
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		return nil
+	}
+	for _, t := range settings.Tokens {
+		if secureCompare(t.Token, token) {
+			return &Principal{Name: t.Name, Roles: t.Roles}
+		}
+	}
+	return nil
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// registerPprof Registers pprof endpoints on router
+func registerPprof(router *mux.Router) {
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+}
+
+// httpHandler Wraps router by authentication and CORS. Registers pprof endpoints in debug mode
+func httpHandler(settings *HTTPSettings, router *mux.Router) http.Handler {
+	if settings.Debug {
+		registerPprof(router)
+	}
+
+	var handler http.Handler = router
+	if settings.Auth.Enable {
+		handler = authMiddleware(&settings.Auth, handler)
+	}
+	wildcard := "*"
+	allowAll := stringInSlice(&wildcard, settings.CORSOrigins)
+	return cors.New(cors.Options{
+		AllowedOrigins:   settings.CORSOrigins,
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowCredentials: !allowAll,
+	}).Handler(handler)
+}
+
+// StartHTTPServer Start HTTP server for registered routes in separate goroutine
+func (app *Application) StartHTTPServer() {
+	settings := &app.settings.HTTPSettings
+	app.server = &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", settings.Port),
+		Handler: httpHandler(settings, app.router),
+	}
+
+	go func() {
+		scheme := "http"
+		if settings.TLSCertFile != "" {
+			scheme = "https"
+		}
+		app.logger.Info("Starting HTTP server", "url", fmt.Sprintf("%s://localhost:%d", scheme, settings.Port), "auth", settings.Auth.Enable, "debug", settings.Debug)
+
+		var err error
+		if settings.TLSCertFile != "" {
+			err = app.server.ListenAndServeTLS(settings.TLSCertFile, settings.TLSKeyFile)
+		} else {
+			err = app.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			app.logger.Error("HTTP server has been stopped", "error", err)
+			os.Exit(1)
+		}
+	}()
+}
+
+// stopHTTPServer Gracefully stops HTTP server. Long-living connections (e.g. MJPEG clients) are closed after timeout
+func (app *Application) stopHTTPServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := app.server.Shutdown(ctx); err != nil {
+		_ = app.server.Close()
+	}
+}
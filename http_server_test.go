@@ -0,0 +1,139 @@
+package ml
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestHTTPHandler Creates handler of protected '/api', admin-only '/admin' and public '/public' routes
+func newTestHTTPHandler(t *testing.T, debug bool) http.Handler {
+	t.Helper()
+	settings := &HTTPSettings{
+		CORSOrigins: []string{"https://dashboard.example"},
+		Debug:       debug,
+		Auth: AuthSettings{
+			Enable: true,
+			Users: []AuthUser{
+				{Username: "admin", Password: "admin-secret", Roles: []string{"admin", "viewer"}},
+				{Username: "guest", Password: "guest-secret", Roles: []string{"viewer"}},
+			},
+			Tokens:      []AuthToken{{Name: "display", Token: "display-token", Roles: []string{"viewer"}}},
+			PublicPaths: []string{"/public"},
+		},
+	}
+	if err := settings.Prepare(8080); err != nil {
+		t.Fatal(err)
+	}
+	principal := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := "anonymous"
+		if p := PrincipalFromRequest(r); p != nil {
+			name = p.Name
+		}
+		_, _ = io.WriteString(w, name)
+	})
+	router := mux.NewRouter()
+	router.Handle("/api", principal)
+	router.Handle("/admin", RequireRole("admin", principal))
+	router.Handle("/public", principal)
+	return httpHandler(settings, router)
+}
+
+func TestHTTPAuthentication(t *testing.T) {
+	handler := newTestHTTPHandler(t, false)
+	tests := []struct {
+		name string
+		url  string
+		// Modifies request to provide credentials
+		auth     func(r *http.Request)
+		expected int
+		// Authenticated principal in case of success
+		principal string
+	}{
+		{"no credentials", "/api", nil, http.StatusUnauthorized, ""},
+		{"basic wrong password", "/api", func(r *http.Request) { r.SetBasicAuth("admin", "guest-secret") }, http.StatusUnauthorized, ""},
+		{"basic unknown user", "/api", func(r *http.Request) { r.SetBasicAuth("root", "admin-secret") }, http.StatusUnauthorized, ""},
+		{"basic", "/api", func(r *http.Request) { r.SetBasicAuth("guest", "guest-secret") }, http.StatusOK, "guest"},
+		{"bearer wrong token", "/api", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong-token") }, http.StatusUnauthorized, ""},
+		{"bearer", "/api", func(r *http.Request) { r.Header.Set("Authorization", "Bearer display-token") }, http.StatusOK, "display"},
+		{"query wrong token", "/api?access_token=wrong-token", nil, http.StatusUnauthorized, ""},
+		{"query", "/api?access_token=display-token", nil, http.StatusOK, "display"},
+		{"public path", "/public", nil, http.StatusOK, "anonymous"},
+		{"public path authenticated", "/public", func(r *http.Request) { r.SetBasicAuth("guest", "guest-secret") }, http.StatusOK, "guest"},
+		{"role without credentials", "/admin", nil, http.StatusUnauthorized, ""},
+		{"wrong role basic", "/admin", func(r *http.Request) { r.SetBasicAuth("guest", "guest-secret") }, http.StatusForbidden, ""},
+		{"wrong role bearer", "/admin", func(r *http.Request) { r.Header.Set("Authorization", "Bearer display-token") }, http.StatusForbidden, ""},
+		{"role", "/admin", func(r *http.Request) { r.SetBasicAuth("admin", "admin-secret") }, http.StatusOK, "admin"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.auth != nil {
+			test.auth(r)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expected, w.Code)
+			continue
+		}
+		switch w.Code {
+		case http.StatusOK:
+			if body := w.Body.String(); body != test.principal {
+				t.Errorf("%s: expected principal '%s', got '%s'", test.name, test.principal, body)
+			}
+		case http.StatusUnauthorized:
+			if w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: WWW-Authenticate header is missing", test.name)
+			}
+		}
+	}
+}
+
+func TestHTTPCORSPreflight(t *testing.T) {
+	handler := newTestHTTPHandler(t, false)
+	// Browsers don't send credentials with preflight requests
+	r := httptest.NewRequest(http.MethodOptions, "/admin", nil)
+	r.Header.Set("Origin", "https://dashboard.example")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK && w.Code != http.StatusNoContent {
+		t.Fatalf("expected successful preflight, got status %d", w.Code)
+	}
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "https://dashboard.example" {
+		t.Errorf("unexpected allowed origin '%s'", origin)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("credentials must be allowed for explicit origins")
+	}
+
+	r = httptest.NewRequest(http.MethodOptions, "/admin", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("origin must not be allowed, got '%s'", origin)
+	}
+}
+
+func TestHTTPPprofRequiresDebug(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		handler := newTestHTTPHandler(t, debug)
+		r := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		r.SetBasicAuth("admin", "admin-secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		expected := http.StatusNotFound
+		if debug {
+			expected = http.StatusOK
+		}
+		if w.Code != expected {
+			t.Errorf("debug %v: expected status %d, got %d", debug, expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewLogger Creates structured logger from provided settings
+func NewLogger(settings LogSettings) (*slog.Logger, error) {
+	level, err := settings.SlogLevel()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't parse log level %s", settings.Level)
+	}
+
+	var w io.Writer
+	switch settings.Output {
+	case "stderr":
+		w = os.Stderr
+	case "stdout":
+		w = os.Stdout
+	default:
+		f, err := os.OpenFile(settings.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't open log file %s", settings.Output)
+		}
+		w = f
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch settings.Format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format '%s'", settings.Format)
+	}
+}
+
+// logThrottle Suppresses repeated log records with the same message.
+// The first record is written immediately, the following ones are counted
+// and reported with the next record written after interval has passed.
+type logThrottle struct {
+	interval time.Duration
+	mu       sync.Mutex
+	entries  map[string]*throttleEntry
+}
+
+type throttleEntry struct {
+	last       time.Time
+	suppressed int
+}
+
+func newLogThrottle(interval time.Duration) *logThrottle {
+	return &logThrottle{
+		interval: interval,
+		entries:  make(map[string]*throttleEntry),
+	}
+}
+
+// Log Writes record to logger unless the same message has been written recently
+func (t *logThrottle) Log(logger *slog.Logger, level slog.Level, msg string, args ...any) {
+	t.mu.Lock()
+	entry, ok := t.entries[msg]
+	if !ok {
+		entry = &throttleEntry{}
+		t.entries[msg] = entry
+	}
+	now := time.Now()
+	if now.Sub(entry.last) < t.interval {
+		entry.suppressed++
+		t.mu.Unlock()
+		return
+	}
+	suppressed := entry.suppressed
+	entry.last = now
+	entry.suppressed = 0
+	t.mu.Unlock()
+
+	if suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+	logger.Log(context.Background(), level, msg, args...)
+}
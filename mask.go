@@ -0,0 +1,156 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Mask Binary instance mask of detected object. Covers Rect of frame, values are 0 or 1 in row-major order
+type Mask struct {
+	Rect image.Rectangle
+	Data []byte
+	// Outline of the largest part of mask in frame coordinates
+	Polygon []image.Point
+}
+
+// maskJSON JSON representation of Mask
+type maskJSON struct {
+	// Top-left corner of mask in frame coordinates
+	Origin [2]int `json:"origin"`
+	// Run-length encoding of box region of mask in row-major order starting with zeros.
+	// It is not COCO RLE, which is column-major and covers the whole image
+	RLE struct {
+		Size   [2]int `json:"size"` // [height, width]
+		Counts []int  `json:"counts"`
+	} `json:"bbox_rle_rowmajor"`
+	Polygon [][2]int `json:"polygon,omitempty"`
+}
+
+// toJSON returns JSON representation of mask
+func (m *Mask) toJSON() *maskJSON {
+	v := &maskJSON{Origin: [2]int{m.Rect.Min.X, m.Rect.Min.Y}}
+	v.RLE.Size = [2]int{m.Rect.Dy(), m.Rect.Dx()}
+	v.RLE.Counts = encodeRLE(m.Data)
+	for _, p := range m.Polygon {
+		v.Polygon = append(v.Polygon, [2]int{p.X, p.Y})
+	}
+	return v
+}
+
+// maskFromJSON Decodes mask from JSON representation
+func maskFromJSON(v *maskJSON) (*Mask, error) {
+	height, width := v.RLE.Size[0], v.RLE.Size[1]
+	data, err := decodeRLE(v.RLE.Counts, width*height)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mask{
+		Rect: image.Rect(v.Origin[0], v.Origin[1], v.Origin[0]+width, v.Origin[1]+height),
+		Data: data,
+	}
+	for _, p := range v.Polygon {
+		m.Polygon = append(m.Polygon, image.Pt(p[0], p[1]))
+	}
+	return m, nil
+}
+
+// Translate Moves mask by offset
+func (m *Mask) Translate(offset image.Point) {
+	m.Rect = m.Rect.Add(offset)
+	for i := range m.Polygon {
+		m.Polygon[i] = m.Polygon[i].Add(offset)
+	}
+}
+
+// Scale Maps mask from coordinates of frame with size 'from' to coordinates of frame with size 'to' (nearest neighbour)
+func (m *Mask) Scale(from, to image.Point) {
+	rect := scaleRect(m.Rect, from, to)
+	srcW, srcH := m.Rect.Dx(), m.Rect.Dy()
+	dstW, dstH := rect.Dx(), rect.Dy()
+	data := make([]byte, dstW*dstH)
+	if srcW > 0 && srcH > 0 {
+		for y := 0; y < dstH; y++ {
+			sy := y * srcH / dstH
+			for x := 0; x < dstW; x++ {
+				data[y*dstW+x] = m.Data[sy*srcW+x*srcW/dstW]
+			}
+		}
+	}
+	for i, p := range m.Polygon {
+		m.Polygon[i] = scaleRect(image.Rectangle{Min: p, Max: p}, from, to).Min
+	}
+	m.Rect = rect
+	m.Data = data
+}
+
+// Mat returns mask as 8-bit single-channel gocv.Mat with values 0 and 255. Caller has to close it
+func (m *Mask) Mat() (gocv.Mat, error) {
+	data := make([]byte, len(m.Data))
+	for i, v := range m.Data {
+		data[i] = v * 255
+	}
+	return gocv.NewMatFromBytes(m.Rect.Dy(), m.Rect.Dx(), gocv.MatTypeCV8U, data)
+}
+
+// outline Finds outline of the largest part of mask
+func (m *Mask) outline() []image.Point {
+	mat, err := m.Mat()
+	if err != nil {
+		return nil
+	}
+	defer mat.Close()
+	contours := gocv.FindContours(mat, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+
+	best, bestArea := -1, 0.0
+	for i := 0; i < contours.Size(); i++ {
+		if area := gocv.ContourArea(contours.At(i)); area > bestArea {
+			best, bestArea = i, area
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	approx := gocv.ApproxPolyDP(contours.At(best), 1.0, true)
+	defer approx.Close()
+	points := approx.ToPoints()
+	for i := range points {
+		points[i] = points[i].Add(m.Rect.Min)
+	}
+	return points
+}
+
+// encodeRLE Encodes binary data as lengths of alternating runs of zeros and ones, starting with zeros
+func encodeRLE(data []byte) []int {
+	counts := []int{}
+	current, run := byte(0), 0
+	for _, v := range data {
+		if v != current {
+			counts = append(counts, run)
+			current, run = v, 0
+		}
+		run++
+	}
+	return append(counts, run)
+}
+
+// decodeRLE Decodes data encoded by encodeRLE
+func decodeRLE(counts []int, size int) ([]byte, error) {
+	data := make([]byte, 0, size)
+	value := byte(0)
+	for _, run := range counts {
+		if run < 0 || len(data)+run > size {
+			return nil, fmt.Errorf("invalid RLE counts for mask of %d pixels", size)
+		}
+		for i := 0; i < run; i++ {
+			data = append(data, value)
+		}
+		value ^= 1
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("RLE counts cover %d of %d pixels", len(data), size)
+	}
+	return data, nil
+}
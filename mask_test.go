@@ -0,0 +1,112 @@
+package ml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestRLERoundTrip(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		counts []int
+	}{
+		{[]byte{}, []int{0}},
+		{[]byte{0, 0, 0}, []int{3}},
+		{[]byte{1, 1}, []int{0, 2}},
+		{[]byte{0, 1, 1, 0, 0, 0, 1}, []int{1, 2, 3, 1}},
+		{[]byte{1, 0, 1, 0}, []int{0, 1, 1, 1, 1}},
+	}
+	for _, test := range tests {
+		counts := encodeRLE(test.data)
+		if fmt.Sprint(counts) != fmt.Sprint(test.counts) {
+			t.Errorf("%v: expected counts %v, got %v", test.data, test.counts, counts)
+		}
+		data, err := decodeRLE(counts, len(test.data))
+		if err != nil {
+			t.Errorf("%v: %v", test.data, err)
+			continue
+		}
+		if !bytes.Equal(data, test.data) {
+			t.Errorf("%v: decoded %v", test.data, data)
+		}
+	}
+}
+
+func TestDecodeRLEInvalid(t *testing.T) {
+	for _, counts := range [][]int{{2, -1, 3}, {3, 2}, {1, 1}} {
+		if _, err := decodeRLE(counts, 4); err == nil {
+			t.Errorf("%v: expected error for mask of 4 pixels", counts)
+		}
+	}
+}
+
+func TestMaskJSONRoundTrip(t *testing.T) {
+	mask := &Mask{
+		Rect: image.Rect(10, 20, 13, 22),
+		Data: []byte{
+			0, 1, 1,
+			1, 1, 0,
+		},
+		Polygon: []image.Point{{10, 20}, {12, 20}, {11, 21}},
+	}
+	data, err := json.Marshal(&DetectedObject{Rect: mask.Rect, ClassName: "person", Mask: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Box-relative row-major RLE must not be mistaken for COCO RLE
+	if !strings.Contains(string(data), `"bbox_rle_rowmajor":{"size":[2,3],"counts":[1,4,1]}`) {
+		t.Errorf("unexpected mask encoding %s", data)
+	}
+
+	var decoded DetectedObject
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Mask == nil {
+		t.Fatal("mask hasn't been decoded")
+	}
+	if decoded.Mask.Rect != mask.Rect || !bytes.Equal(decoded.Mask.Data, mask.Data) || fmt.Sprint(decoded.Mask.Polygon) != fmt.Sprint(mask.Polygon) {
+		t.Errorf("expected %+v, got %+v", mask, decoded.Mask)
+	}
+}
+
+func TestMaskScale(t *testing.T) {
+	original := []byte{
+		1, 0,
+		0, 1,
+	}
+	mask := &Mask{
+		Rect:    image.Rect(2, 4, 4, 6),
+		Data:    append([]byte(nil), original...),
+		Polygon: []image.Point{{2, 4}, {4, 6}},
+	}
+	mask.Scale(image.Pt(10, 10), image.Pt(20, 20))
+	if mask.Rect != image.Rect(4, 8, 8, 12) {
+		t.Errorf("unexpected scaled rectangle %v", mask.Rect)
+	}
+	expected := []byte{
+		1, 1, 0, 0,
+		1, 1, 0, 0,
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+	}
+	if !bytes.Equal(mask.Data, expected) {
+		t.Errorf("expected scaled data %v, got %v", expected, mask.Data)
+	}
+	if fmt.Sprint(mask.Polygon) != fmt.Sprint([]image.Point{{4, 8}, {8, 12}}) {
+		t.Errorf("unexpected scaled polygon %v", mask.Polygon)
+	}
+
+	// Downscaling back restores original mask
+	mask.Scale(image.Pt(20, 20), image.Pt(10, 10))
+	if mask.Rect != image.Rect(2, 4, 4, 6) || !bytes.Equal(mask.Data, original) {
+		t.Errorf("expected original mask after round trip, got %v %v", mask.Rect, mask.Data)
+	}
+	if fmt.Sprint(mask.Polygon) != fmt.Sprint([]image.Point{{2, 4}, {4, 6}}) {
+		t.Errorf("unexpected polygon after round trip %v", mask.Polygon)
+	}
+}
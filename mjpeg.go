@@ -0,0 +1,97 @@
+package ml
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattn/go-mjpeg"
+	"gocv.io/x/gocv"
+)
+
+// MJPEGStreamer Streams annotated (and optionally raw) frames as MJPEG over HTTP
+type MJPEGStreamer struct {
+	settings   *MjpegSettings
+	annotated  *mjpeg.Stream
+	raw        *mjpeg.Stream
+	unredacted *mjpeg.Stream
+	// Role required for watching unredacted stream
+	unredactedRole string
+}
+
+// NewMJPEGStreamer Creates MJPEGStreamer. Frames are sent to every client at most 'max_fps' times per second
+func NewMJPEGStreamer(settings *MjpegSettings) *MJPEGStreamer {
+	ms := &MJPEGStreamer{
+		settings: settings,
+	}
+	ms.annotated = mjpeg.NewStreamWithInterval(ms.interval())
+	if settings.RawEnable {
+		ms.raw = mjpeg.NewStreamWithInterval(ms.interval())
+	}
+	return ms
+}
+
+// interval returns minimal interval between frames sent to client
+func (ms *MJPEGStreamer) interval() time.Duration {
+	if ms.settings.MaxFPS <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / ms.settings.MaxFPS)
+}
+
+// RegisterRoutes Registers stream endpoints on router. "/" is kept as alias of annotated stream
+func (ms *MJPEGStreamer) RegisterRoutes(router *mux.Router) {
+	router.Handle("/", ms.annotated).Methods(http.MethodGet)
+	router.Handle("/stream/annotated", ms.annotated).Methods(http.MethodGet)
+	if ms.raw != nil {
+		router.Handle("/stream/raw", ms.raw).Methods(http.MethodGet)
+	}
+	if ms.unredacted != nil {
+		router.Handle("/stream/unredacted", RequireRole(ms.unredactedRole, ms.unredacted)).Methods(http.MethodGet)
+	}
+}
+
+// EnableUnredacted Streams annotated frames before redaction on /stream/unredacted to principals with provided role.
+// Must be called before RegisterRoutes
+func (ms *MJPEGStreamer) EnableUnredacted(role string) {
+	ms.unredacted = mjpeg.NewStreamWithInterval(ms.interval())
+	ms.unredactedRole = role
+}
+
+// Consume implements FrameSink. Frames are encoded only when stream has clients
+func (ms *MJPEGStreamer) Consume(frame *FrameData, _ []*DetectedObject) error {
+	if err := ms.update(ms.annotated, frame.ImgScaled); err != nil {
+		return err
+	}
+	if ms.unredacted != nil {
+		if err := ms.update(ms.unredacted, frame.ImgUnredacted); err != nil {
+			return err
+		}
+	}
+	if ms.raw != nil {
+		return ms.update(ms.raw, frame.ImgSource)
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (ms *MJPEGStreamer) Close() error {
+	if ms.raw != nil {
+		_ = ms.raw.Close()
+	}
+	if ms.unredacted != nil {
+		_ = ms.unredacted.Close()
+	}
+	return ms.annotated.Close()
+}
+
+func (ms *MJPEGStreamer) update(stream *mjpeg.Stream, img gocv.Mat) error {
+	if stream.NWatch() == 0 || img.Empty() {
+		return nil
+	}
+	data, err := EncodeImage(gocv.JPEGFileExt, img, gocv.IMWriteJpegQuality, ms.settings.Quality)
+	if err != nil {
+		return err
+	}
+	return stream.Update(data)
+}
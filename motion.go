@@ -0,0 +1,116 @@
+package ml
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Values of foreground mask of MOG2 are 255 for moving pixels and 127 for shadows
+const mog2ForegroundThreshold = 200
+
+// MotionDetector Decides whether frame has to be passed to neural network
+type MotionDetector struct {
+	settings *MotionSettings
+
+	mog2    gocv.BackgroundSubtractorMOG2
+	prev    gocv.Mat
+	gray    gocv.Mat
+	motion  gocv.Mat
+	mask    gocv.Mat // 255 for pixels taken into account, 0 for masked regions
+	maskFor image.Point
+
+	lastMotion    time.Time
+	lastInference time.Time
+	force         bool
+}
+
+// NewMotionDetector Creates MotionDetector. Settings have to be prepared
+func NewMotionDetector(settings *MotionSettings) *MotionDetector {
+	md := &MotionDetector{
+		settings: settings,
+		prev:     gocv.NewMat(),
+		gray:     gocv.NewMat(),
+		motion:   gocv.NewMat(),
+		mask:     gocv.NewMat(),
+		force:    true,
+	}
+	if settings.Method == "mog2" {
+		md.mog2 = gocv.NewBackgroundSubtractorMOG2WithParams(settings.History, settings.Threshold, true)
+	}
+	return md
+}
+
+// ShouldDetect Feeds frame to motion detector and reports whether inference is needed
+func (md *MotionDetector) ShouldDetect(img gocv.Mat, timestamp time.Time) bool {
+	if md.detectMotion(img) {
+		md.lastMotion = timestamp
+	}
+	run := md.force ||
+		timestamp.Sub(md.lastMotion).Seconds() <= md.settings.HoldSec ||
+		(md.settings.MaxIdleSec > 0 && timestamp.Sub(md.lastInference).Seconds() >= md.settings.MaxIdleSec)
+	if run {
+		md.force = false
+		md.lastInference = timestamp
+	}
+	return run
+}
+
+// Reset Forces inference on next frame, e.g. when detection has been re-enabled
+func (md *MotionDetector) Reset() {
+	md.force = true
+}
+
+// Close Free memory for underlying objects
+func (md *MotionDetector) Close() error {
+	if md.settings.Method == "mog2" {
+		_ = md.mog2.Close()
+	}
+	_ = md.prev.Close()
+	_ = md.gray.Close()
+	_ = md.motion.Close()
+	return md.mask.Close()
+}
+
+// detectMotion Checks whether area of changed pixels outside of masked regions is big enough
+func (md *MotionDetector) detectMotion(img gocv.Mat) bool {
+	gocv.CvtColor(img, &md.gray, gocv.ColorBGRToGray)
+	gocv.GaussianBlur(md.gray, &md.gray, image.Pt(21, 21), 0, 0, gocv.BorderDefault)
+
+	if md.settings.Method == "mog2" {
+		md.mog2.Apply(md.gray, &md.motion)
+		gocv.Threshold(md.motion, &md.motion, mog2ForegroundThreshold, 255, gocv.ThresholdBinary)
+	} else {
+		if md.prev.Empty() || md.prev.Cols() != md.gray.Cols() || md.prev.Rows() != md.gray.Rows() {
+			md.gray.CopyTo(&md.prev)
+			return false
+		}
+		gocv.AbsDiff(md.gray, md.prev, &md.motion)
+		gocv.Threshold(md.motion, &md.motion, float32(md.settings.Threshold), 255, gocv.ThresholdBinary)
+		md.gray.CopyTo(&md.prev)
+	}
+
+	if len(md.settings.Masks) != 0 {
+		md.updateMask(image.Pt(img.Cols(), img.Rows()))
+		gocv.BitwiseAnd(md.motion, md.mask, &md.motion)
+	}
+	area := img.Cols() * img.Rows()
+	return area != 0 && float64(gocv.CountNonZero(md.motion))/float64(area) >= md.settings.MinAreaRatio
+}
+
+// updateMask Rebuilds mask of ignored regions when frame size changes
+func (md *MotionDetector) updateMask(size image.Point) {
+	if md.maskFor == size {
+		return
+	}
+	_ = md.mask.Close()
+	md.mask = gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 0, 0, 0), size.Y, size.X, gocv.MatTypeCV8U)
+	polygons := make([]Polygon, 0, len(md.settings.Masks))
+	for _, mask := range md.settings.Masks {
+		polygons = append(polygons, mask)
+	}
+	fillPolygons(&md.mask, polygons, size, image.Point{}, color.RGBA{})
+	md.maskFor = size
+}
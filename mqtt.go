@@ -0,0 +1,130 @@
+package ml
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+)
+
+const mqttConnectTimeout = 10 * time.Second
+
+// presenceMessage Retained state of zone
+type presenceMessage struct {
+	Stream    string    `json:"stream"`
+	Zone      string    `json:"zone"`
+	Occupied  bool      `json:"occupied"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MQTTPublisher Publishes events and presence state of zones to MQTT broker
+type MQTTPublisher struct {
+	settings *MQTTSettings
+	client   mqtt.Client
+	logger   *slog.Logger
+	// Last published occupancy per zone
+	presence map[string]int
+}
+
+// NewMQTTPublisher Connects to configured broker
+func NewMQTTPublisher(settings *MQTTSettings, logger *slog.Logger) (*MQTTPublisher, error) {
+	logger = logger.With("component", "mqtt", "broker", settings.Broker)
+	opts := mqtt.NewClientOptions().
+		AddBroker(settings.Broker).
+		SetClientID(settings.ClientID).
+		SetUsername(settings.Username).
+		SetPassword(settings.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectTimeout(mqttConnectTimeout).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			logger.Warn("Connection to MQTT broker has been lost", "error", err)
+		})
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttConnectTimeout) {
+		logger.Warn("MQTT broker is not reachable yet. Retrying in background")
+	} else if err := token.Error(); err != nil {
+		return nil, errors.Wrapf(err, "Can't connect to MQTT broker %s", settings.Broker)
+	}
+	return NewMQTTPublisherWithClient(settings, client, logger), nil
+}
+
+// NewMQTTPublisherWithClient Creates publisher on top of already configured client
+func NewMQTTPublisherWithClient(settings *MQTTSettings, client mqtt.Client, logger *slog.Logger) *MQTTPublisher {
+	return &MQTTPublisher{
+		settings: settings,
+		client:   client,
+		logger:   logger,
+		presence: make(map[string]int),
+	}
+}
+
+// HandleEvents implements EventHandler
+func (p *MQTTPublisher) HandleEvents(batch *EventBatch) error {
+	for _, event := range batch.Events {
+		if !stringInSlice((*string)(&event.Type), p.settings.Events) {
+			continue
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal event")
+		}
+		topic := strings.NewReplacer(
+			"{stream}", topicLevel(event.Stream),
+			"{class}", topicLevel(event.Object.ClassName),
+			"{type}", string(event.Type),
+		).Replace(p.settings.TopicTemplate)
+		p.publish(topic, false, payload)
+	}
+
+	for zone, count := range batch.Occupancy {
+		if last, ok := p.presence[zone]; ok && last == count {
+			continue
+		}
+		p.presence[zone] = count
+		payload, err := json.Marshal(presenceMessage{
+			Stream:    batch.Stream,
+			Zone:      zone,
+			Occupied:  count > 0,
+			Count:     count,
+			Timestamp: batch.Timestamp,
+		})
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal presence state")
+		}
+		topic := strings.NewReplacer(
+			"{stream}", topicLevel(batch.Stream),
+			"{zone}", topicLevel(zone),
+		).Replace(p.settings.PresenceTopicTemplate)
+		p.publish(topic, true, payload)
+	}
+	return nil
+}
+
+// Close implements EventHandler
+func (p *MQTTPublisher) Close() error {
+	p.client.Disconnect(250)
+	return nil
+}
+
+// publish Publishes message without blocking processing of frames. Errors are logged once delivery is finished
+func (p *MQTTPublisher) publish(topic string, retained bool, payload []byte) {
+	token := p.client.Publish(topic, byte(p.settings.QoS), retained, payload)
+	go func() {
+		<-token.Done()
+		if err := token.Error(); err != nil {
+			p.logger.Warn("Can't publish MQTT message", "topic", topic, "error", err)
+		}
+	}()
+}
+
+// topicLevel Replaces characters which are not allowed inside of single MQTT topic level
+func topicLevel(s string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_").Replace(s)
+}
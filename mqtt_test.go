@@ -0,0 +1,257 @@
+package ml
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+const mqttTestTimeout = 5 * time.Second
+
+// publishRecorder Broker hook capturing PUBLISH packets as they were received from clients
+type publishRecorder struct {
+	mqttserver.HookBase
+	published chan packets.Packet
+}
+
+func (h *publishRecorder) ID() string {
+	return "publish-recorder"
+}
+
+func (h *publishRecorder) Provides(b byte) bool {
+	return b == mqttserver.OnPublished
+}
+
+func (h *publishRecorder) OnPublished(_ *mqttserver.Client, pk packets.Packet) {
+	h.published <- pk
+}
+
+// startTestBroker Starts in-process broker accepting users 'ml' and 'viewer' with password 'secret'
+func startTestBroker(t *testing.T) (string, *mqttserver.Server, *publishRecorder) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := mqttserver.New(&mqttserver.Options{Logger: logger})
+	ledger := &auth.Ledger{Users: auth.Users{
+		"ml":     {Username: "ml", Password: "secret", ACL: auth.Filters{"#": auth.WriteOnly}},
+		"viewer": {Username: "viewer", Password: "secret", ACL: auth.Filters{"#": auth.ReadOnly}},
+	}}
+	if err := server.AddHook(new(auth.Hook), &auth.Options{Ledger: ledger}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := &publishRecorder{published: make(chan packets.Packet, 16)}
+	if err := server.AddHook(recorder, nil); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddListener(listeners.NewNet("test", ln)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return "tcp://" + ln.Addr().String(), server, recorder
+}
+
+// newTestPublisher Connects publisher of stream 'front door' to broker
+func newTestPublisher(t *testing.T, broker string, settings *MQTTSettings) *MQTTPublisher {
+	t.Helper()
+	settings.Broker = broker
+	settings.Username = "ml"
+	settings.Password = "secret"
+	settings.Prepare("front door")
+	publisher, err := NewMQTTPublisher(settings, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publisher
+}
+
+// subscribe Connects separate client to broker and subscribes it to filter
+func subscribe(t *testing.T, broker, filter string, qos byte) <-chan mqtt.Message {
+	t.Helper()
+	messages := make(chan mqtt.Message, 16)
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID("viewer-" + t.Name()).
+		SetUsername("viewer").
+		SetPassword("secret")
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); !token.WaitTimeout(mqttTestTimeout) || token.Error() != nil {
+		t.Fatalf("can't connect subscriber: %v", token.Error())
+	}
+	t.Cleanup(func() { client.Disconnect(250) })
+	token := client.Subscribe(filter, qos, func(_ mqtt.Client, msg mqtt.Message) {
+		messages <- msg
+	})
+	if !token.WaitTimeout(mqttTestTimeout) || token.Error() != nil {
+		t.Fatalf("can't subscribe to %s: %v", filter, token.Error())
+	}
+	return messages
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(mqttTestTimeout):
+		t.Fatal("message hasn't been delivered")
+	}
+	panic("unreachable")
+}
+
+func TestMQTTPublisherConnects(t *testing.T) {
+	broker, server, _ := startTestBroker(t)
+	publisher := newTestPublisher(t, broker, &MQTTSettings{})
+	if !publisher.client.IsConnected() {
+		t.Fatal("publisher is not connected")
+	}
+	client, ok := server.Clients.Get("ml-front_door")
+	if !ok {
+		t.Fatal("broker doesn't know client 'ml-front_door'")
+	}
+	if username := string(client.Properties.Username); username != "ml" {
+		t.Errorf("expected username 'ml', got '%s'", username)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if publisher.client.IsConnected() {
+		t.Error("publisher has not been disconnected")
+	}
+}
+
+func TestMQTTPublisherEvents(t *testing.T) {
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	person := &DetectedObject{Rect: image.Rect(10, 20, 50, 120), ClassName: "person", Confidence: 0.9, TrackID: 3}
+	batch := &EventBatch{
+		Stream:    "front door",
+		Timestamp: timestamp,
+		Events: []*Event{
+			{Type: EventDetection, Stream: "front door", Timestamp: timestamp, Object: person},
+			{Type: EventTrackNew, Stream: "front door", Timestamp: timestamp, Object: person, Zones: []string{"porch"}},
+		},
+	}
+	for _, qos := range []byte{0, 1, 2} {
+		broker, _, recorder := startTestBroker(t)
+		messages := subscribe(t, broker, "cameras/#", 2)
+		publisher := newTestPublisher(t, broker, &MQTTSettings{QoS: int(qos), TopicTemplate: "cameras/{stream}/{type}/{class}"})
+		if err := publisher.HandleEvents(batch); err != nil {
+			t.Fatal(err)
+		}
+
+		// Packet as it was sent by publisher
+		pk := receive(t, recorder.published)
+		if pk.TopicName != "cameras/front_door/track_new/person" {
+			t.Errorf("QoS %d: unexpected topic %s", qos, pk.TopicName)
+		}
+		if pk.FixedHeader.Qos != qos || pk.FixedHeader.Retain {
+			t.Errorf("QoS %d: expected QoS %d without retain flag, got QoS %d retained %v", qos, qos, pk.FixedHeader.Qos, pk.FixedHeader.Retain)
+		}
+
+		msg := receive(t, messages)
+		if msg.Topic() != "cameras/front_door/track_new/person" || msg.Qos() != qos {
+			t.Errorf("QoS %d: delivered message to %s with QoS %d", qos, msg.Topic(), msg.Qos())
+		}
+		var event Event
+		if err := json.Unmarshal(msg.Payload(), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != EventTrackNew || event.Stream != "front door" || !event.Timestamp.Equal(timestamp) {
+			t.Errorf("QoS %d: unexpected event %+v", qos, event)
+		}
+		if event.Object == nil || event.Object.ClassName != "person" || event.Object.TrackID != 3 || event.Object.Rect != person.Rect {
+			t.Errorf("QoS %d: unexpected object %+v", qos, event.Object)
+		}
+		if len(event.Zones) != 1 || event.Zones[0] != "porch" {
+			t.Errorf("QoS %d: unexpected zones %v", qos, event.Zones)
+		}
+
+		if err := publisher.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// Detection events are not published by default
+		select {
+		case pk := <-recorder.published:
+			t.Errorf("QoS %d: unexpected message to %s", qos, pk.TopicName)
+		default:
+		}
+	}
+}
+
+func TestMQTTPublisherPresence(t *testing.T) {
+	broker, _, recorder := startTestBroker(t)
+	publisher := newTestPublisher(t, broker, &MQTTSettings{QoS: 1})
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	occupancy := []map[string]int{
+		{"porch": 0},
+		{"porch": 2},
+		// Unchanged state is not published again
+		{"porch": 2},
+		{"porch": 1},
+	}
+	for i, zones := range occupancy {
+		batch := &EventBatch{Stream: "front door", Timestamp: timestamp.Add(time.Duration(i) * time.Second), Occupancy: zones}
+		if err := publisher.HandleEvents(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, count := range []int{0, 2, 1} {
+		pk := receive(t, recorder.published)
+		if pk.TopicName != "ml/front_door/zones/porch/presence" {
+			t.Errorf("message %d: unexpected topic %s", i, pk.TopicName)
+		}
+		if pk.FixedHeader.Qos != 1 || !pk.FixedHeader.Retain {
+			t.Errorf("message %d: expected retained message with QoS 1, got QoS %d retained %v", i, pk.FixedHeader.Qos, pk.FixedHeader.Retain)
+		}
+		var state presenceMessage
+		if err := json.Unmarshal(pk.Payload, &state); err != nil {
+			t.Fatal(err)
+		}
+		if state.Count != count || state.Occupied != (count > 0) {
+			t.Errorf("message %d: expected count %d, got %+v", i, count, state)
+		}
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case pk := <-recorder.published:
+		t.Errorf("unchanged state has been published again: %s", pk.Payload)
+	default:
+	}
+
+	// Subscriber connected after publisher has gone gets the last state
+	msg := receive(t, subscribe(t, broker, "ml/+/zones/+/presence", 1))
+	if !msg.Retained() {
+		t.Error("expected retained message")
+	}
+	var state presenceMessage
+	if err := json.Unmarshal(msg.Payload(), &state); err != nil {
+		t.Fatal(err)
+	}
+	state.Timestamp = time.Time{}
+	if expected := (presenceMessage{Stream: "front door", Zone: "porch", Occupied: true, Count: 1}); state != expected {
+		t.Errorf("expected %+v, got %+v", expected, state)
+	}
+}
+
+func TestTopicLevel(t *testing.T) {
+	if got := topicLevel("a/b+c#d e"); got != "a_b_c_d_e" {
+		t.Errorf("unexpected topic level %s", got)
+	}
+}
@@ -0,0 +1,185 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	overlayFont    = gocv.FontHersheyPlain
+	overlayPadding = 2
+)
+
+// OverlayRenderer Draws detections and frame information on frames
+type OverlayRenderer struct {
+	settings   *OverlaySettings
+	streamName string
+
+	lastFrame time.Time
+	fps       float64
+}
+
+// NewOverlayRenderer Creates OverlayRenderer. Settings have to be prepared
+func NewOverlayRenderer(settings *OverlaySettings, streamName string) *OverlayRenderer {
+	return &OverlayRenderer{
+		settings:   settings,
+		streamName: streamName,
+	}
+}
+
+// Render Draws detections and frame information on provided image. FPS is measured by timestamps of rendered frames
+func (or *OverlayRenderer) Render(img *gocv.Mat, detected []*DetectedObject, timestamp time.Time) {
+	if !or.lastFrame.IsZero() {
+		if dt := timestamp.Sub(or.lastFrame).Seconds(); dt > 0 {
+			or.fps = ema(or.fps, 1/dt)
+		}
+	}
+	or.lastFrame = timestamp
+	or.Draw(img, detected, timestamp)
+}
+
+// Draw Draws detections and frame information on provided image without update of FPS
+func (or *OverlayRenderer) Draw(img *gocv.Mat, detected []*DetectedObject, timestamp time.Time) {
+	for _, detection := range detected {
+		or.drawDetection(img, detection)
+	}
+	or.drawInfo(img, timestamp)
+}
+
+func (or *OverlayRenderer) drawDetection(img *gocv.Mat, detection *DetectedObject) {
+	s := or.settings
+	c := s.ClassColor(detection.ClassID, detection.ClassName)
+	if *s.Masks && detection.Mask != nil {
+		or.drawMask(img, detection.Mask, c)
+	}
+	if *s.Skeleton && len(detection.Keypoints) != 0 {
+		or.drawSkeleton(img, detection.Keypoints, c)
+	}
+	if *s.Boxes {
+		gocv.Rectangle(img, detection.Rect, c, s.Thickness)
+	}
+
+	var parts []string
+	if *s.ClassName {
+		parts = append(parts, detection.ClassName)
+	}
+	if *s.Confidence {
+		parts = append(parts, fmt.Sprintf("%.0f%%", detection.Confidence*100))
+	}
+	if *s.TrackID && detection.TrackID != 0 {
+		parts = append(parts, fmt.Sprintf("#%d", detection.TrackID))
+	}
+	if *s.Attributes && len(detection.Attributes) != 0 {
+		names := make([]string, 0, len(detection.Attributes))
+		for name := range detection.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			parts = append(parts, detection.Attributes[name].Label)
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	or.drawLabel(img, strings.Join(parts, " "), detection.Rect.Min, c)
+}
+
+// drawMask Blends color into image under mask
+func (or *OverlayRenderer) drawMask(img *gocv.Mat, mask *Mask, c color.RGBA) {
+	if !mask.Rect.In(image.Rect(0, 0, img.Cols(), img.Rows())) || mask.Rect.Empty() {
+		return
+	}
+	maskMat, err := mask.Mat()
+	if err != nil {
+		return
+	}
+	defer maskMat.Close()
+
+	region := img.Region(mask.Rect)
+	defer region.Close()
+	fill := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(float64(c.B), float64(c.G), float64(c.R), 0), region.Rows(), region.Cols(), region.Type())
+	defer fill.Close()
+	blended := gocv.NewMat()
+	defer blended.Close()
+	gocv.AddWeighted(region, 1-or.settings.MaskAlpha, fill, or.settings.MaskAlpha, 0, &blended)
+	blended.CopyToWithMask(&region, maskMat)
+}
+
+// drawSkeleton Draws confident keypoints and connections between them
+func (or *OverlayRenderer) drawSkeleton(img *gocv.Mat, keypoints []Keypoint, c color.RGBA) {
+	threshold := float32(or.settings.KeypointThreshold)
+	for _, pair := range poseSkeleton {
+		if pair[0] >= len(keypoints) || pair[1] >= len(keypoints) {
+			continue
+		}
+		a, b := keypoints[pair[0]], keypoints[pair[1]]
+		if a.Confidence >= threshold && b.Confidence >= threshold {
+			gocv.Line(img, a.Point, b.Point, c, or.settings.Thickness)
+		}
+	}
+	for _, keypoint := range keypoints {
+		if keypoint.Confidence >= threshold {
+			gocv.Circle(img, keypoint.Point, 2*or.settings.Thickness+1, c, -1)
+		}
+	}
+}
+
+// drawInfo Draws stream name, timestamp and FPS in top-left corner of image
+func (or *OverlayRenderer) drawInfo(img *gocv.Mat, timestamp time.Time) {
+	s := or.settings
+	var parts []string
+	if *s.StreamName {
+		parts = append(parts, or.streamName)
+	}
+	if *s.Timestamp {
+		parts = append(parts, timestamp.Format("2006-01-02 15:04:05.000"))
+	}
+	if *s.FPS {
+		parts = append(parts, fmt.Sprintf("%.1f FPS", or.fps))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	// Label is moved down to the top edge of image
+	or.drawLabel(img, strings.Join(parts, " | "), image.Pt(0, 0), s.textColor)
+}
+
+// drawLabel Draws text above provided point. Text is moved inside image when it doesn't fit
+func (or *OverlayRenderer) drawLabel(img *gocv.Mat, text string, at image.Point, c color.RGBA) {
+	s := or.settings
+	size, baseline := gocv.GetTextSizeWithBaseline(text, overlayFont, s.FontScale, s.Thickness)
+	height := size.Y + baseline + 2*overlayPadding
+	if at.Y < height {
+		at.Y = height
+	}
+	if maxX := img.Cols() - size.X - 2*overlayPadding; at.X > maxX {
+		at.X = maxX
+	}
+	if at.X < 0 {
+		at.X = 0
+	}
+
+	textColor := c
+	if *s.LabelBackground {
+		background := image.Rect(at.X, at.Y-height, at.X+size.X+2*overlayPadding, at.Y)
+		gocv.Rectangle(img, background, c, -1)
+		textColor = contrastColor(c)
+	}
+	gocv.PutText(img, text, image.Pt(at.X+overlayPadding, at.Y-baseline-overlayPadding), overlayFont, s.FontScale, textColor, s.Thickness)
+}
+
+// contrastColor returns black or white color, whichever is more readable on provided background
+func contrastColor(background color.RGBA) color.RGBA {
+	luma := 0.299*float64(background.R) + 0.587*float64(background.G) + 0.114*float64(background.B)
+	if luma > 128 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+}
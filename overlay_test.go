@@ -0,0 +1,153 @@
+package ml
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// newOverlaySettings Creates prepared settings with only toggles returned by enable turned on
+func newOverlaySettings(t *testing.T, enable func(s *OverlaySettings) []**bool) *OverlaySettings {
+	t.Helper()
+	settings := &OverlaySettings{ClassColors: map[string]string{"car": "#FF0000"}}
+	off := false
+	on := true
+	for _, toggle := range []**bool{
+		&settings.Boxes, &settings.ClassName, &settings.Confidence, &settings.TrackID, &settings.Attributes,
+		&settings.Masks, &settings.Skeleton, &settings.LabelBackground, &settings.Timestamp, &settings.FPS, &settings.StreamName,
+	} {
+		*toggle = &off
+	}
+	if enable != nil {
+		for _, toggle := range enable(settings) {
+			*toggle = &on
+		}
+	}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+// newBlackImage Creates black BGR image. Mats created by size only are not initialized
+func newBlackImage(width, height int) gocv.Mat {
+	return gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), height, width, gocv.MatTypeCV8UC3)
+}
+
+// pixelColor returns color of pixel of BGR image
+func pixelColor(img gocv.Mat, p image.Point) color.RGBA {
+	v := img.GetVecbAt(p.Y, p.X)
+	return color.RGBA{R: v[2], G: v[1], B: v[0], A: 255}
+}
+
+var overlayTestCar = &DetectedObject{Rect: image.Rect(50, 60, 150, 160), ClassName: "car", Confidence: 0.9, TrackID: 7}
+
+func TestOverlayDrawsBoxes(t *testing.T) {
+	settings := newOverlaySettings(t, func(s *OverlaySettings) []**bool {
+		return []**bool{&s.Boxes}
+	})
+	img := newBlackImage(200, 200)
+	defer img.Close()
+	NewOverlayRenderer(settings, "cam").Draw(&img, []*DetectedObject{overlayTestCar}, time.Now())
+
+	red := color.RGBA{R: 255, A: 255}
+	black := color.RGBA{A: 255}
+	for _, p := range []image.Point{{50, 100}, {100, 60}, {100, 160}} {
+		if c := pixelColor(img, p); c != red {
+			t.Errorf("border pixel %v: expected %v, got %v", p, red, c)
+		}
+	}
+	for _, p := range []image.Point{{100, 110}, {10, 10}, {190, 190}} {
+		if c := pixelColor(img, p); c != black {
+			t.Errorf("pixel %v outside of border: expected %v, got %v", p, black, c)
+		}
+	}
+}
+
+func TestOverlayDrawsLabelBackground(t *testing.T) {
+	for _, background := range []bool{true, false} {
+		settings := newOverlaySettings(t, func(s *OverlaySettings) []**bool {
+			if background {
+				return []**bool{&s.ClassName, &s.Confidence, &s.LabelBackground}
+			}
+			return []**bool{&s.ClassName, &s.Confidence}
+		})
+
+		img := newBlackImage(200, 200)
+		NewOverlayRenderer(settings, "cam").Draw(&img, []*DetectedObject{overlayTestCar}, time.Now())
+
+		size, baseline := gocv.GetTextSizeWithBaseline("car 90%", overlayFont, settings.FontScale, settings.Thickness)
+		height := size.Y + baseline + 2*overlayPadding
+		// Corners of label background are covered by padding, not by text
+		corners := []image.Point{
+			{overlayTestCar.Rect.Min.X, overlayTestCar.Rect.Min.Y - height},
+			{overlayTestCar.Rect.Min.X + size.X + 2*overlayPadding - 1, overlayTestCar.Rect.Min.Y - 1},
+		}
+		expected := color.RGBA{A: 255}
+		if background {
+			expected = color.RGBA{R: 255, A: 255}
+		}
+		for _, p := range corners {
+			if c := pixelColor(img, p); c != expected {
+				t.Errorf("background %v: pixel %v expected %v, got %v", background, p, expected, c)
+			}
+		}
+		label := img.Region(image.Rect(overlayTestCar.Rect.Min.X, overlayTestCar.Rect.Min.Y-height, overlayTestCar.Rect.Min.X+size.X+2*overlayPadding, overlayTestCar.Rect.Min.Y))
+		if nonBlackPixels(t, label) == 0 {
+			t.Errorf("background %v: label hasn't been drawn", background)
+		}
+		// Boxes are disabled
+		if c := pixelColor(img, image.Pt(50, 100)); c != (color.RGBA{A: 255}) {
+			t.Errorf("background %v: box has been drawn", background)
+		}
+		label.Close()
+		img.Close()
+	}
+}
+
+func TestOverlayDisabledToggles(t *testing.T) {
+	settings := newOverlaySettings(t, nil)
+	img := newBlackImage(200, 200)
+	defer img.Close()
+	detection := *overlayTestCar
+	detection.Keypoints = []Keypoint{{Point: image.Pt(60, 70), Confidence: 1}, {Point: image.Pt(80, 90), Confidence: 1}}
+	detection.Attributes = map[string]Attribute{"color": {Label: "red", Confidence: 1}}
+	NewOverlayRenderer(settings, "cam").Render(&img, []*DetectedObject{&detection}, time.Now())
+	if n := nonBlackPixels(t, img); n != 0 {
+		t.Errorf("expected blank image, got %d drawn pixels", n)
+	}
+}
+
+func TestOverlayDrawsInfo(t *testing.T) {
+	settings := newOverlaySettings(t, func(s *OverlaySettings) []**bool {
+		return []**bool{&s.StreamName, &s.Timestamp}
+	})
+	img := newBlackImage(400, 200)
+	defer img.Close()
+	NewOverlayRenderer(settings, "cam").Draw(&img, nil, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+
+	size, baseline := gocv.GetTextSizeWithBaseline("cam | 2026-10-19 12:00:00.000", overlayFont, settings.FontScale, settings.Thickness)
+	height := size.Y + baseline + 2*overlayPadding
+	top := img.Region(image.Rect(0, 0, img.Cols(), height))
+	defer top.Close()
+	rest := img.Region(image.Rect(0, height, img.Cols(), img.Rows()))
+	defer rest.Close()
+	if nonBlackPixels(t, top) == 0 {
+		t.Error("frame information hasn't been drawn")
+	}
+	if n := nonBlackPixels(t, rest); n != 0 {
+		t.Errorf("frame information is expected in top-left corner only, got %d pixels below", n)
+	}
+}
+
+func TestContrastColor(t *testing.T) {
+	if c := contrastColor(color.RGBA{R: 255, G: 255, A: 255}); c != (color.RGBA{A: 255}) {
+		t.Errorf("expected black text on yellow, got %v", c)
+	}
+	if c := contrastColor(color.RGBA{B: 255, A: 255}); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("expected white text on blue, got %v", c)
+	}
+}
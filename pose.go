@@ -0,0 +1,152 @@
+package ml
+
+import (
+	"encoding/json"
+	"image"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Keypoints of COCO pose models
+const (
+	KeypointNose = iota
+	KeypointLeftEye
+	KeypointRightEye
+	KeypointLeftEar
+	KeypointRightEar
+	KeypointLeftShoulder
+	KeypointRightShoulder
+	KeypointLeftElbow
+	KeypointRightElbow
+	KeypointLeftWrist
+	KeypointRightWrist
+	KeypointLeftHip
+	KeypointRightHip
+	KeypointLeftKnee
+	KeypointRightKnee
+	KeypointLeftAnkle
+	KeypointRightAnkle
+	poseKeypoints
+)
+
+// poseSkeleton Pairs of connected keypoints
+var poseSkeleton = [][2]int{
+	{KeypointLeftAnkle, KeypointLeftKnee}, {KeypointLeftKnee, KeypointLeftHip},
+	{KeypointRightAnkle, KeypointRightKnee}, {KeypointRightKnee, KeypointRightHip},
+	{KeypointLeftHip, KeypointRightHip},
+	{KeypointLeftShoulder, KeypointLeftHip}, {KeypointRightShoulder, KeypointRightHip},
+	{KeypointLeftShoulder, KeypointRightShoulder},
+	{KeypointLeftShoulder, KeypointLeftElbow}, {KeypointLeftElbow, KeypointLeftWrist},
+	{KeypointRightShoulder, KeypointRightElbow}, {KeypointRightElbow, KeypointRightWrist},
+	{KeypointLeftEye, KeypointRightEye}, {KeypointNose, KeypointLeftEye}, {KeypointNose, KeypointRightEye},
+	{KeypointLeftEye, KeypointLeftEar}, {KeypointRightEye, KeypointRightEar},
+}
+
+// Keypoint Body keypoint of detected person
+type Keypoint struct {
+	Point      image.Point
+	Confidence float32
+}
+
+// MarshalJSON implements json.Marshaler. Keypoint is encoded as [x, y, confidence]
+func (k Keypoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([3]float32{float32(k.Point.X), float32(k.Point.Y), k.Confidence})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (k *Keypoint) UnmarshalJSON(data []byte) error {
+	var v [3]float32
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	k.Point = image.Pt(int(v[0]), int(v[1]))
+	k.Confidence = v[2]
+	return nil
+}
+
+// PoseDetector Detector for YOLOv8 pose models exported to ONNX. Returns persons with COCO keypoints
+type PoseDetector struct {
+	settings *NeuralNetworkSettings
+
+	mu          sync.Mutex
+	net         gocv.Net
+	layersNames []string
+}
+
+// NewPoseDetector Reads pose model from settings
+func NewPoseDetector(settings *NeuralNetworkSettings) (*PoseDetector, error) {
+	net, layersNames, err := readNet(settings.Model, "", settings.Backend, settings.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &PoseDetector{settings: settings, net: net, layersNames: layersNames}, nil
+}
+
+// Detect implements Detector
+func (pd *PoseDetector) Detect(img gocv.Mat, params *DetectionParams) ([]*DetectedObject, error) {
+	inputSize := image.Pt(pd.settings.InputSize, pd.settings.InputSize)
+	blob := gocv.BlobFromImage(img, yoloScaleFactor, inputSize, yoloMean, true, false)
+	defer blob.Close()
+
+	pd.mu.Lock()
+	pd.net.SetInput(blob, "")
+	outputs := pd.net.ForwardLayers(pd.layersNames)
+	pd.mu.Unlock()
+	defer closeMats(outputs)
+	return pd.postprocess(outputs[0], params, image.Pt(img.Cols(), img.Rows()))
+}
+
+// Close implements Detector
+func (pd *PoseDetector) Close() error {
+	return pd.net.Close()
+}
+
+// postprocess Decodes output [1, 4 + 1 + 17 * 3, boxes]: box, person score and (x, y, confidence) of keypoints
+func (pd *PoseDetector) postprocess(output gocv.Mat, params *DetectionParams, frameSize image.Point) ([]*DetectedObject, error) {
+	dims := output.Size()
+	if len(dims) != 3 || dims[1] != 5+3*poseKeypoints {
+		return nil, errors.Errorf("Unexpected shape of pose output %v", dims)
+	}
+	data, err := output.DataPtrFloat32()
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't extract predictions")
+	}
+	if len(params.NetClasses) == 0 {
+		return nil, errors.New("Classes are empty")
+	}
+	className := params.NetClasses[0]
+	if !stringInSlice(&className, params.Filters) {
+		return nil, nil
+	}
+
+	boxes := dims[2]
+	at := func(box, attr int) float32 {
+		return data[attr*boxes+box]
+	}
+	sx := float64(frameSize.X) / float64(pd.settings.InputSize)
+	sy := float64(frameSize.Y) / float64(pd.settings.InputSize)
+	var objects []*DetectedObject
+	var bboxes []image.Rectangle
+	var confidences []float32
+	for i := 0; i < boxes; i++ {
+		confidence := at(i, 4)
+		if confidence <= params.ConfThreshold {
+			continue
+		}
+		cx, cy, w, h := float64(at(i, 0))*sx, float64(at(i, 1))*sy, float64(at(i, 2))*sx, float64(at(i, 3))*sy
+		rect := image.Rect(int(cx-w/2), int(cy-h/2), int(cx+w/2), int(cy+h/2))
+		keypoints := make([]Keypoint, poseKeypoints)
+		for k := range keypoints {
+			keypoints[k] = Keypoint{
+				Point:      image.Pt(int(float64(at(i, 5+3*k))*sx), int(float64(at(i, 6+3*k))*sy)),
+				Confidence: at(i, 7+3*k),
+			}
+		}
+		objects = append(objects, &DetectedObject{Rect: rect, ClassName: className, Confidence: confidence, Keypoints: keypoints})
+		bboxes = append(bboxes, rect)
+		confidences = append(confidences, confidence)
+	}
+	return applyNMS(objects, bboxes, confidences, params.ConfThreshold, params.NmsThreshold), nil
+}
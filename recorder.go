@@ -0,0 +1,173 @@
+package ml
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// SegmentRecorder Writes annotated (and optionally source) frames to segmented video files with rolling retention
+type SegmentRecorder struct {
+	annotated *segmentWriter
+	raw       *segmentWriter
+}
+
+// NewSegmentRecorder Creates SegmentRecorder writing to configured directory
+func NewSegmentRecorder(settings *RecordingSettings, streamName string, logger *slog.Logger) (*SegmentRecorder, error) {
+	if err := os.MkdirAll(settings.Directory, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "Can't create recordings directory %s", settings.Directory)
+	}
+	logger = logger.With("component", "recorder")
+	sr := &SegmentRecorder{
+		annotated: newSegmentWriter(settings, sanitizeFileName(streamName)+"_annotated", logger),
+	}
+	if settings.RecordRaw {
+		sr.raw = newSegmentWriter(settings, sanitizeFileName(streamName)+"_raw", logger)
+	}
+	return sr, nil
+}
+
+// Consume implements FrameSink
+func (sr *SegmentRecorder) Consume(frame *FrameData, _ []*DetectedObject) error {
+	if err := sr.annotated.write(frame.Timestamp, frame.ImgScaled); err != nil {
+		return err
+	}
+	if sr.raw != nil {
+		return sr.raw.write(frame.Timestamp, frame.ImgSource)
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (sr *SegmentRecorder) Close() error {
+	err := sr.annotated.close()
+	if sr.raw != nil {
+		if rawErr := sr.raw.close(); err == nil {
+			err = rawErr
+		}
+	}
+	return err
+}
+
+// segmentWriter Writes frames of single stream to files rolled by duration
+type segmentWriter struct {
+	settings *RecordingSettings
+	prefix   string
+	logger   *slog.Logger
+
+	writer    *gocv.VideoWriter
+	path      string
+	startedAt time.Time
+	width     int
+	height    int
+}
+
+func newSegmentWriter(settings *RecordingSettings, prefix string, logger *slog.Logger) *segmentWriter {
+	return &segmentWriter{
+		settings: settings,
+		prefix:   prefix,
+		logger:   logger,
+	}
+}
+
+func (sw *segmentWriter) write(timestamp time.Time, img gocv.Mat) error {
+	if img.Empty() {
+		return nil
+	}
+	rotate := sw.writer != nil && timestamp.Sub(sw.startedAt).Seconds() >= sw.settings.SegmentDurationSec
+	resized := sw.writer != nil && (img.Cols() != sw.width || img.Rows() != sw.height)
+	if rotate || resized {
+		if err := sw.close(); err != nil {
+			return err
+		}
+	}
+	if sw.writer == nil {
+		if err := sw.open(timestamp, img.Cols(), img.Rows()); err != nil {
+			return err
+		}
+	}
+	if err := sw.writer.Write(img); err != nil {
+		return errors.Wrapf(err, "Can't write frame to %s", sw.path)
+	}
+	return nil
+}
+
+func (sw *segmentWriter) open(timestamp time.Time, width, height int) error {
+	name := fmt.Sprintf("%s_%s.%s", sw.prefix, timestamp.Format("20060102T150405"), sw.settings.Container)
+	path := filepath.Join(sw.settings.Directory, name)
+	writer, err := gocv.VideoWriterFile(path, sw.settings.Codec, sw.settings.FPS, width, height, true)
+	if err != nil {
+		return errors.Wrapf(err, "Can't open video writer for %s", path)
+	}
+	if !writer.IsOpened() {
+		_ = writer.Close()
+		return fmt.Errorf("video writer for %s is not opened (codec %s)", path, sw.settings.Codec)
+	}
+	sw.writer = writer
+	sw.path = path
+	sw.startedAt = timestamp
+	sw.width = width
+	sw.height = height
+	sw.logger.Info("Recording segment has been started", "file", path)
+	return nil
+}
+
+func (sw *segmentWriter) close() error {
+	if sw.writer == nil {
+		return nil
+	}
+	err := sw.writer.Close()
+	sw.writer = nil
+	if err != nil {
+		return errors.Wrapf(err, "Can't close %s", sw.path)
+	}
+	sw.applyRetention()
+	return nil
+}
+
+// applyRetention Removes segments exceeding configured count or age. Current segment is never removed
+func (sw *segmentWriter) applyRetention() {
+	if sw.settings.MaxSegments == 0 && sw.settings.MaxAgeHours == 0 {
+		return
+	}
+	entries, err := os.ReadDir(sw.settings.Directory)
+	if err != nil {
+		sw.logger.Error("Can't list recordings directory", "error", err)
+		return
+	}
+	var segments []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), sw.prefix+"_") {
+			segments = append(segments, entry.Name())
+		}
+	}
+	// Names contain timestamp, so lexical order is chronological
+	sort.Strings(segments)
+
+	deadline := time.Now().Add(-time.Duration(sw.settings.MaxAgeHours * float64(time.Hour)))
+	for i, name := range segments {
+		path := filepath.Join(sw.settings.Directory, name)
+		tooMany := sw.settings.MaxSegments > 0 && len(segments)-i > sw.settings.MaxSegments
+		tooOld := false
+		if sw.settings.MaxAgeHours > 0 {
+			if info, err := os.Stat(path); err == nil {
+				tooOld = info.ModTime().Before(deadline)
+			}
+		}
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			sw.logger.Warn("Can't remove recording segment", "file", path, "error", err)
+			continue
+		}
+		sw.logger.Info("Recording segment has been removed by retention policy", "file", path)
+	}
+}
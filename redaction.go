@@ -0,0 +1,102 @@
+package ml
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// Redactor Hides detected objects of configured classes on scaled and source images of frame
+type Redactor struct {
+	settings *RedactionSettings
+	// Keep annotated copy of frame before redaction in FrameData.ImgUnredacted
+	keepUnredacted bool
+}
+
+// NewRedactor Creates Redactor. Settings have to be prepared
+func NewRedactor(settings *RedactionSettings, keepUnredacted bool) *Redactor {
+	return &Redactor{
+		settings:       settings,
+		keepUnredacted: keepUnredacted,
+	}
+}
+
+// Apply Redacts boxes of detected objects. Boxes are in coordinates of scaled image.
+// Boxes predicted by tracker or reused from previous frame (FrameData.Predicted) are padded more generously.
+// Redaction fails closed: frames without any trusted boxes (neither fresh nor predicted) are blacked out completely
+func (rd *Redactor) Apply(frame *FrameData, detected []*DetectedObject) {
+	if rd.keepUnredacted {
+		frame.ImgScaled.CopyTo(&frame.ImgUnredacted)
+	}
+	padding := rd.settings.Padding
+	switch {
+	case frame.Inferred:
+	case frame.Predicted:
+		padding = rd.settings.PredictedPadding
+	default:
+		blackout(&frame.ImgScaled)
+		blackout(&frame.ImgSource)
+		return
+	}
+	scaledSize := image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows())
+	sourceSize := image.Pt(frame.ImgSource.Cols(), frame.ImgSource.Rows())
+	for _, detection := range detected {
+		if !stringInSlice(&detection.ClassName, rd.settings.Classes) {
+			continue
+		}
+		rect := padRect(detection.Rect, padding)
+		rd.redact(&frame.ImgScaled, rect)
+		// Source image is always a separate Mat, even when its size equals size of scaled image
+		if sourceSize != scaledSize {
+			rect = scaleRect(rect, scaledSize, sourceSize)
+		}
+		rd.redact(&frame.ImgSource, rect)
+	}
+}
+
+func (rd *Redactor) redact(img *gocv.Mat, rect image.Rectangle) {
+	rect = rect.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if rect.Empty() {
+		return
+	}
+	if rd.settings.Method == "black" {
+		gocv.Rectangle(img, rect, color.RGBA{A: 255}, -1)
+		return
+	}
+
+	// Region shares memory with image, so changes are applied in place
+	region := img.Region(rect)
+	defer region.Close()
+	switch rd.settings.Method {
+	case "pixelate":
+		blockSize := math.Max(float64(rect.Dx()), float64(rect.Dy())) / float64(rd.settings.PixelBlocks)
+		small := gocv.NewMat()
+		defer small.Close()
+		gocv.Resize(region, &small, image.Pt(int(math.Max(1, float64(rect.Dx())/blockSize)), int(math.Max(1, float64(rect.Dy())/blockSize))), 0, 0, gocv.InterpolationLinear)
+		gocv.Resize(small, &region, rect.Size(), 0, 0, gocv.InterpolationNearestNeighbor)
+	default:
+		kernel := int(float64(min(rect.Dx(), rect.Dy())) * rd.settings.BlurRatio)
+		kernel |= 1 // kernel size must be odd
+		if kernel < 3 {
+			kernel = 3
+		}
+		gocv.GaussianBlur(region, &region, image.Pt(kernel, kernel), 0, 0, gocv.BorderReflect)
+	}
+}
+
+// blackout Fills whole image with black
+func blackout(img *gocv.Mat) {
+	if img.Empty() {
+		return
+	}
+	gocv.Rectangle(img, image.Rect(0, 0, img.Cols(), img.Rows()), color.RGBA{A: 255}, -1)
+}
+
+// padRect Extends rectangle by fraction of its size on every side
+func padRect(r image.Rectangle, padding float64) image.Rectangle {
+	dx := int(float64(r.Dx()) * padding)
+	dy := int(float64(r.Dy()) * padding)
+	return image.Rect(r.Min.X-dx, r.Min.Y-dy, r.Max.X+dx, r.Max.Y+dy)
+}
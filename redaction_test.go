@@ -0,0 +1,116 @@
+package ml
+
+import (
+	"image"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func newWhiteFrame(scaled, source image.Point) *FrameData {
+	frame := NewFrameData()
+	white := gocv.NewScalar(255, 255, 255, 0)
+	frame.ImgScaled = gocv.NewMatWithSizeFromScalar(white, scaled.Y, scaled.X, gocv.MatTypeCV8UC3)
+	frame.ImgSource = gocv.NewMatWithSizeFromScalar(white, source.Y, source.X, gocv.MatTypeCV8UC3)
+	return frame
+}
+
+// nonBlackPixels Counts pixels with any non-zero channel
+func nonBlackPixels(t *testing.T, img gocv.Mat) int {
+	t.Helper()
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	return gocv.CountNonZero(gray)
+}
+
+// isBlack Checks whether pixel of BGR image is black
+func isBlack(img gocv.Mat, p image.Point) bool {
+	v := img.GetVecbAt(p.Y, p.X)
+	return v[0] == 0 && v[1] == 0 && v[2] == 0
+}
+
+func TestRedactorFailsClosedWithoutTrustedDetections(t *testing.T) {
+	settings := &RedactionSettings{Enable: true, Classes: []string{"person"}, Method: "blur"}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	// No boxes at all when detection is disabled, or boxes of tracker after failed inference
+	for name, detected := range map[string][]*DetectedObject{
+		"no detections":        nil,
+		"untrusted detections": {{Rect: image.Rect(5, 5, 15, 15), ClassName: "person"}},
+	} {
+		frame := newWhiteFrame(image.Pt(40, 30), image.Pt(80, 60))
+		frame.Inferred = false
+		frame.Predicted = false
+		NewRedactor(settings, false).Apply(frame, detected)
+		if n := nonBlackPixels(t, frame.ImgScaled); n != 0 {
+			t.Errorf("%s: scaled image has %d visible pixels", name, n)
+		}
+		if n := nonBlackPixels(t, frame.ImgSource); n != 0 {
+			t.Errorf("%s: source image has %d visible pixels", name, n)
+		}
+		frame.Close()
+	}
+}
+
+func TestRedactorRedactsFreshDetections(t *testing.T) {
+	settings := &RedactionSettings{Enable: true, Classes: []string{"person"}, Method: "black"}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	for _, sourceSize := range []image.Point{{80, 60}, {40, 30}} {
+		frame := newWhiteFrame(image.Pt(40, 30), sourceSize)
+		frame.Inferred = true
+		NewRedactor(settings, false).Apply(frame, []*DetectedObject{
+			{Rect: image.Rect(10, 10, 20, 20), ClassName: "person"},
+			{Rect: image.Rect(25, 5, 35, 15), ClassName: "car"},
+		})
+
+		scale := sourceSize.X / 40
+		if !isBlack(frame.ImgScaled, image.Pt(15, 15)) {
+			t.Errorf("source %v: person must be redacted on scaled image", sourceSize)
+		}
+		if !isBlack(frame.ImgSource, image.Pt(15*scale, 15*scale)) {
+			t.Errorf("source %v: person must be redacted on source image", sourceSize)
+		}
+		if isBlack(frame.ImgScaled, image.Pt(30, 10)) || isBlack(frame.ImgSource, image.Pt(30*scale, 10*scale)) {
+			t.Errorf("source %v: car must not be redacted", sourceSize)
+		}
+		if isBlack(frame.ImgScaled, image.Pt(2, 2)) || isBlack(frame.ImgSource, image.Pt(2, 2)) {
+			t.Errorf("source %v: background must not be redacted", sourceSize)
+		}
+		frame.Close()
+	}
+}
+
+func TestRedactorRedactsPredictedDetections(t *testing.T) {
+	settings := &RedactionSettings{Enable: true, Classes: []string{"person"}, Method: "black", Padding: 0.05, PredictedPadding: 0.3}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	detected := []*DetectedObject{{Rect: image.Rect(10, 10, 20, 20), ClassName: "person"}}
+	// Pixel outside of fresh box with regular padding, but inside of predicted box with generous padding
+	margin := image.Pt(8, 15)
+
+	fresh := newWhiteFrame(image.Pt(40, 30), image.Pt(40, 30))
+	defer fresh.Close()
+	fresh.Inferred = true
+	NewRedactor(settings, false).Apply(fresh, detected)
+	if isBlack(fresh.ImgScaled, margin) {
+		t.Error("fresh box must be padded by 'padding' only")
+	}
+
+	predicted := newWhiteFrame(image.Pt(40, 30), image.Pt(40, 30))
+	defer predicted.Close()
+	predicted.Predicted = true
+	NewRedactor(settings, false).Apply(predicted, detected)
+	for _, img := range []gocv.Mat{predicted.ImgScaled, predicted.ImgSource} {
+		if !isBlack(img, image.Pt(15, 15)) || !isBlack(img, margin) {
+			t.Error("predicted box must be redacted with 'predicted_padding'")
+		}
+		if isBlack(img, image.Pt(35, 25)) {
+			t.Error("frame with predicted boxes must not be blacked out")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ml
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// ROI Region of interest of detection with exclusion masks
+type ROI struct {
+	// Region of interest. Nil means whole frame
+	area       *Zone
+	exclusions []*Zone
+}
+
+// NewROI Creates ROI from prepared settings
+func NewROI(settings *ROISettings) *ROI {
+	roi := &ROI{}
+	if len(settings.Polygon) != 0 {
+		roi.area = &Zone{Name: "roi", Anchor: settings.Anchor, Polygon: settings.Polygon}
+	}
+	for _, mask := range settings.ExclusionMasks {
+		roi.exclusions = append(roi.exclusions, &Zone{Name: "exclusion", Anchor: settings.Anchor, Polygon: mask})
+	}
+	return roi
+}
+
+// Bounds returns bounding rectangle of region of interest for frame of provided size
+func (roi *ROI) Bounds(frameSize image.Point) image.Rectangle {
+	frame := image.Rectangle{Max: frameSize}
+	if roi.area == nil {
+		return frame
+	}
+	minX, minY, maxX, maxY := 1.0, 1.0, 0.0, 0.0
+	for _, p := range roi.area.Polygon {
+		minX, minY = math.Min(minX, p[0]), math.Min(minY, p[1])
+		maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+	}
+	return image.Rect(
+		int(minX*float64(frameSize.X)), int(minY*float64(frameSize.Y)),
+		int(math.Ceil(maxX*float64(frameSize.X))), int(math.Ceil(maxY*float64(frameSize.Y))),
+	).Intersect(frame)
+}
+
+// Crop returns copy of bounding rectangle of region of interest with pixels outside of region and inside of exclusion masks filled black
+func (roi *ROI) Crop(img gocv.Mat) (gocv.Mat, image.Rectangle) {
+	frameSize := image.Pt(img.Cols(), img.Rows())
+	bounds := roi.Bounds(frameSize)
+	region := img.Region(bounds)
+	defer region.Close()
+	var crop gocv.Mat
+	if roi.area != nil {
+		inside := gocv.Zeros(region.Rows(), region.Cols(), gocv.MatTypeCV8U)
+		defer inside.Close()
+		fillPolygons(&inside, []Polygon{roi.area.Polygon}, frameSize, bounds.Min, color.RGBA{R: 255, G: 255, B: 255})
+		crop = gocv.Zeros(region.Rows(), region.Cols(), region.Type())
+		region.CopyToWithMask(&crop, inside)
+	} else {
+		crop = region.Clone()
+	}
+	polygons := make([]Polygon, 0, len(roi.exclusions))
+	for _, zone := range roi.exclusions {
+		polygons = append(polygons, zone.Polygon)
+	}
+	fillPolygons(&crop, polygons, frameSize, bounds.Min, color.RGBA{})
+	return crop, bounds
+}
+
+// Filter Discards detections outside of region of interest and inside of exclusion masks. Boxes are given in coordinates of frame of provided size
+func (roi *ROI) Filter(detected []*DetectedObject, frameSize image.Point) []*DetectedObject {
+	filtered := detected[:0]
+	for _, detection := range detected {
+		if roi.area != nil && !roi.area.Contains(detection.Rect, frameSize) {
+			continue
+		}
+		if len(zoneNames(roi.exclusions, detection.Rect, frameSize)) != 0 {
+			continue
+		}
+		filtered = append(filtered, detection)
+	}
+	return filtered
+}
+
+// fillPolygons Fills normalized polygons on image which is located at offset of frame of provided size
+func fillPolygons(img *gocv.Mat, polygons []Polygon, frameSize, offset image.Point, c color.RGBA) {
+	if len(polygons) == 0 {
+		return
+	}
+	points := make([][]image.Point, 0, len(polygons))
+	for _, polygon := range polygons {
+		pts := make([]image.Point, 0, len(polygon))
+		for _, p := range polygon {
+			pts = append(pts, image.Pt(int(p[0]*float64(frameSize.X))-offset.X, int(p[1]*float64(frameSize.Y))-offset.Y))
+		}
+		points = append(points, pts)
+	}
+	pv := gocv.NewPointsVectorFromPoints(points)
+	defer pv.Close()
+	gocv.FillPoly(img, pv, c)
+}
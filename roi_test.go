@@ -0,0 +1,107 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+func newTestROI(t *testing.T, settings *ROISettings) *ROI {
+	t.Helper()
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return NewROI(settings)
+}
+
+func TestROISettingsRectToPolygon(t *testing.T) {
+	settings := &ROISettings{Rect: []float64{0.25, 0.25, 0.5, 0.5}}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]float64{{0.25, 0.25}, {0.75, 0.25}, {0.75, 0.75}, {0.25, 0.75}}
+	if fmt.Sprint(settings.Polygon) != fmt.Sprint(expected) {
+		t.Errorf("expected polygon %v, got %v", expected, settings.Polygon)
+	}
+	if settings.Anchor != "center" {
+		t.Errorf("expected default anchor 'center', got '%s'", settings.Anchor)
+	}
+
+	// Polygon takes precedence over rectangle
+	polygon := [][2]float64{{0, 0}, {1, 0}, {0, 1}}
+	settings = &ROISettings{Rect: []float64{0.25, 0.25, 0.5, 0.5}, Polygon: polygon}
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(settings.Polygon) != fmt.Sprint(polygon) {
+		t.Errorf("expected polygon %v, got %v", polygon, settings.Polygon)
+	}
+}
+
+func TestROISettingsInvalid(t *testing.T) {
+	tests := map[string]*ROISettings{
+		"short rect":                  {Rect: []float64{0, 0, 1}},
+		"rect outside of frame":       {Rect: []float64{0.5, 0.5, 0.6, 0.2}},
+		"two points polygon":          {Polygon: [][2]float64{{0, 0}, {1, 1}}},
+		"polygon outside of frame":    {Polygon: [][2]float64{{0, 0}, {1.2, 0}, {0, 1}}},
+		"two points mask":             {ExclusionMasks: [][][2]float64{{{0, 0}, {1, 1}}}},
+		"mask outside of frame":       {ExclusionMasks: [][][2]float64{{{0, 0}, {1, 0}, {0, 1}}, {{-0.1, 0}, {1, 0}, {0, 1}}}},
+		"mask outside of frame (max)": {ExclusionMasks: [][][2]float64{{{0, 0}, {1, 0}, {0, 1.5}}}},
+	}
+	for name, settings := range tests {
+		if err := settings.Prepare(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestROIFilterAnchors(t *testing.T) {
+	frameSize := image.Pt(100, 100)
+	// Left 3/4 of frame with lower half excluded
+	settings := func(anchor string) *ROISettings {
+		return &ROISettings{
+			Rect:           []float64{0, 0, 0.75, 1},
+			ExclusionMasks: [][][2]float64{{{0, 0.5}, {1, 0.5}, {1, 1}, {0, 1}}},
+			Anchor:         anchor,
+		}
+	}
+	// Center of box is above of mask, bottom of box is inside of it
+	crossing := &DetectedObject{Rect: image.Rect(20, 20, 40, 70), ClassName: "person"}
+	above := &DetectedObject{Rect: image.Rect(20, 10, 40, 30), ClassName: "person"}
+	below := &DetectedObject{Rect: image.Rect(20, 60, 40, 90), ClassName: "person"}
+	outside := &DetectedObject{Rect: image.Rect(80, 10, 95, 30), ClassName: "person"}
+
+	tests := []struct {
+		anchor   string
+		expected []*DetectedObject
+	}{
+		{"center", []*DetectedObject{crossing, above}},
+		{"bottom", []*DetectedObject{above}},
+	}
+	for _, test := range tests {
+		roi := newTestROI(t, settings(test.anchor))
+		filtered := roi.Filter([]*DetectedObject{crossing, above, below, outside}, frameSize)
+		if fmt.Sprint(filtered) != fmt.Sprint(test.expected) {
+			t.Errorf("anchor %s: expected %v, got %v", test.anchor, test.expected, filtered)
+		}
+	}
+}
+
+func TestROIBounds(t *testing.T) {
+	if bounds := newTestROI(t, &ROISettings{}).Bounds(image.Pt(100, 60)); bounds != image.Rect(0, 0, 100, 60) {
+		t.Errorf("expected whole frame without region, got %v", bounds)
+	}
+	roi := newTestROI(t, &ROISettings{Rect: []float64{0.25, 0.25, 0.5, 0.5}})
+	if bounds := roi.Bounds(image.Pt(100, 60)); bounds != image.Rect(25, 15, 75, 45) {
+		t.Errorf("unexpected bounds %v", bounds)
+	}
+	// Fractional pixels are covered by bounds
+	if bounds := roi.Bounds(image.Pt(99, 59)); bounds != image.Rect(24, 14, 75, 45) {
+		t.Errorf("unexpected bounds of odd frame %v", bounds)
+	}
+	// Region touching frame edges never exceeds frame
+	roi = newTestROI(t, &ROISettings{Polygon: [][2]float64{{0, 0}, {1, 0.5}, {1, 1}}})
+	if bounds := roi.Bounds(image.Pt(101, 61)); bounds != image.Rect(0, 0, 101, 61) {
+		t.Errorf("expected bounds within frame, got %v", bounds)
+	}
+}
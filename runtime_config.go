@@ -0,0 +1,100 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RuntimeConfig Settings which can be changed while application is running
+type RuntimeConfig struct {
+	NeuralNetworkEnable bool     `json:"neural_network_enable"`
+	ConfThreshold       float64  `json:"conf_threshold"`
+	NmsThreshold        float64  `json:"nms_threshold"`
+	TargetClasses       []string `json:"target_classes"`
+	// Read only
+	NetClasses []string `json:"net_classes"`
+}
+
+// RuntimeConfigPatch Partial update of RuntimeConfig
+type RuntimeConfigPatch struct {
+	NeuralNetworkEnable *bool     `json:"neural_network_enable"`
+	ConfThreshold       *float64  `json:"conf_threshold"`
+	NmsThreshold        *float64  `json:"nms_threshold"`
+	TargetClasses       *[]string `json:"target_classes"`
+}
+
+// RuntimeConfig returns current values of settings which can be changed at runtime
+func (settings *AppSettings) RuntimeConfig() RuntimeConfig {
+	settings.RLock()
+	defer settings.RUnlock()
+	return RuntimeConfig{
+		NeuralNetworkEnable: settings.NeuralNetworkSettings.Enable,
+		ConfThreshold:       settings.NeuralNetworkSettings.ConfThreshold,
+		NmsThreshold:        settings.NeuralNetworkSettings.NmsThreshold,
+		TargetClasses:       settings.NeuralNetworkSettings.TargetClasses,
+		NetClasses:          settings.NeuralNetworkSettings.NetClasses,
+	}
+}
+
+// ApplyRuntimeConfig Validates and applies partial update of runtime settings
+func (settings *AppSettings) ApplyRuntimeConfig(patch RuntimeConfigPatch) error {
+	if patch.ConfThreshold != nil && (*patch.ConfThreshold <= 0 || *patch.ConfThreshold >= 1) {
+		return fmt.Errorf("'conf_threshold' must be in (0, 1)")
+	}
+	if patch.NmsThreshold != nil && (*patch.NmsThreshold <= 0 || *patch.NmsThreshold >= 1) {
+		return fmt.Errorf("'nms_threshold' must be in (0, 1)")
+	}
+	if patch.TargetClasses != nil {
+		for i := range *patch.TargetClasses {
+			if !stringInSlice(&(*patch.TargetClasses)[i], settings.NeuralNetworkSettings.NetClasses) {
+				return fmt.Errorf("unknown class '%s'", (*patch.TargetClasses)[i])
+			}
+		}
+	}
+
+	settings.Lock()
+	defer settings.Unlock()
+	if patch.NeuralNetworkEnable != nil {
+		settings.NeuralNetworkSettings.Enable = *patch.NeuralNetworkEnable
+	}
+	if patch.ConfThreshold != nil {
+		settings.NeuralNetworkSettings.ConfThreshold = *patch.ConfThreshold
+	}
+	if patch.NmsThreshold != nil {
+		settings.NeuralNetworkSettings.NmsThreshold = *patch.NmsThreshold
+	}
+	if patch.TargetClasses != nil {
+		// Slice is replaced (not modified), so readers holding previous one are safe
+		settings.NeuralNetworkSettings.TargetClasses = append([]string(nil), *patch.TargetClasses...)
+	}
+	return nil
+}
+
+func (app *Application) serveConfig(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(app.settings.RuntimeConfig())
+}
+
+func (app *Application) serveConfigUpdate(w http.ResponseWriter, r *http.Request) {
+	var patch RuntimeConfigPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := app.settings.ApplyRuntimeConfig(patch); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	app.logger.Info("Runtime configuration has been changed", "principal", principalName(r))
+	app.serveConfig(w, r)
+}
+
+func principalName(r *http.Request) string {
+	if p := PrincipalFromRequest(r); p != nil {
+		return p.Name
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package ml
+
+import (
+	"log/slog"
+	"math"
+	"time"
+)
+
+const (
+	// Rate of inferences is increased when average latency is below this fraction of budget
+	schedulerRelaxRatio = 0.7
+	// Number of inferences observed after every change of rate before the next change
+	schedulerSettleInferences = 3
+	// Step of target FPS adaptation
+	schedulerFPSStep = 0.8
+)
+
+// DetectionScheduler Decides which frames are passed to neural network
+type DetectionScheduler struct {
+	settings *DetectionRateSettings
+	logger   *slog.Logger
+
+	interval int
+	// Current maximal number of inferences per second in "fps" mode
+	fps           float64
+	frames        int
+	lastFrame     time.Time
+	lastInference time.Time
+	// Average time between frames in seconds
+	frameSec float64
+	// Average latency of frames passed to neural network
+	latencyMs float64
+	// Number of inferences since last change of rate
+	sinceAdapted int
+}
+
+// NewDetectionScheduler Creates DetectionScheduler. Settings have to be prepared
+func NewDetectionScheduler(settings *DetectionRateSettings, logger *slog.Logger) *DetectionScheduler {
+	return &DetectionScheduler{
+		settings: settings,
+		logger:   logger.With("component", "scheduler"),
+		interval: settings.Interval,
+		fps:      settings.TargetFPS,
+	}
+}
+
+// ShouldDetect reports whether inference has to be run on frame
+func (ds *DetectionScheduler) ShouldDetect(timestamp time.Time) bool {
+	if !ds.lastFrame.IsZero() {
+		if dt := timestamp.Sub(ds.lastFrame).Seconds(); dt > 0 {
+			ds.frameSec = ema(ds.frameSec, dt)
+		}
+	}
+	ds.lastFrame = timestamp
+
+	var run bool
+	if ds.settings.Mode == "fps" {
+		run = ds.lastInference.IsZero() || timestamp.Sub(ds.lastInference).Seconds() >= 1/ds.fps
+	} else {
+		run = ds.frames%ds.interval == 0
+		ds.frames++
+	}
+	if run {
+		ds.lastInference = timestamp
+	}
+	return run
+}
+
+// Observe Adapts rate of inferences to end-to-end latency of frame. Has to be called only for frames passed to neural network:
+// latency of skipped frames doesn't include inference and would hide its cost
+func (ds *DetectionScheduler) Observe(latency time.Duration) {
+	if ds.settings.LatencyBudgetMs <= 0 {
+		return
+	}
+	ds.latencyMs = ema(ds.latencyMs, float64(latency)/float64(time.Millisecond))
+	ds.sinceAdapted++
+	// Let average settle for a couple of inferences after every change
+	if ds.sinceAdapted < schedulerSettleInferences {
+		return
+	}
+	overBudget := ds.latencyMs > ds.settings.LatencyBudgetMs
+	underBudget := ds.latencyMs < schedulerRelaxRatio*ds.settings.LatencyBudgetMs
+	if ds.settings.Mode == "fps" {
+		switch {
+		case overBudget && ds.fps > ds.settings.MinFPS:
+			ds.setFPS(math.Max(ds.fps*schedulerFPSStep, ds.settings.MinFPS))
+		case underBudget && ds.fps < ds.settings.TargetFPS:
+			ds.setFPS(math.Min(ds.fps/schedulerFPSStep, ds.settings.TargetFPS))
+		}
+		return
+	}
+	switch {
+	case overBudget && ds.interval < ds.settings.MaxInterval:
+		ds.setInterval(ds.interval + 1)
+	case underBudget && ds.interval > ds.settings.Interval:
+		ds.setInterval(ds.interval - 1)
+	}
+}
+
+// Interval returns current interval between inferences in frames
+func (ds *DetectionScheduler) Interval() int {
+	return ds.interval
+}
+
+// FPS returns current maximal number of inferences per second in "fps" mode
+func (ds *DetectionScheduler) FPS() float64 {
+	return ds.fps
+}
+
+// InferencePeriod returns expected time between inferences. Zero until frame rate is known in "interval" mode
+func (ds *DetectionScheduler) InferencePeriod() time.Duration {
+	if ds.settings.Mode == "fps" {
+		return time.Duration(float64(time.Second) / ds.fps)
+	}
+	return time.Duration(ds.frameSec * float64(ds.interval) * float64(time.Second))
+}
+
+func (ds *DetectionScheduler) setInterval(interval int) {
+	ds.logger.Debug("Detection interval has been changed", "interval", interval, "latency_ms", ds.latencyMs)
+	ds.interval = interval
+	ds.frames = 0
+	ds.sinceAdapted = 0
+}
+
+func (ds *DetectionScheduler) setFPS(fps float64) {
+	ds.logger.Debug("Detection FPS has been changed", "fps", fps, "latency_ms", ds.latencyMs)
+	ds.fps = fps
+	ds.sinceAdapted = 0
+}
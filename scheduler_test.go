@@ -0,0 +1,104 @@
+package ml
+
+import (
+	"image"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T, settings *DetectionRateSettings) *DetectionScheduler {
+	t.Helper()
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return NewDetectionScheduler(settings, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDetectionSchedulerAdaptsInterval(t *testing.T) {
+	ds := newTestScheduler(t, &DetectionRateSettings{Mode: "interval", Interval: 2, LatencyBudgetMs: 100, MaxInterval: 4})
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		if ds.ShouldDetect(start.Add(time.Duration(i) * 40 * time.Millisecond)) {
+			ds.Observe(300 * time.Millisecond)
+		}
+	}
+	if ds.Interval() != 4 {
+		t.Errorf("expected interval to grow up to 4, got %d", ds.Interval())
+	}
+	if period := ds.InferencePeriod(); period < 150*time.Millisecond || period > 170*time.Millisecond {
+		t.Errorf("expected inference period of 4 frames of 40 ms, got %v", period)
+	}
+
+	for i := 100; i < 300; i++ {
+		if ds.ShouldDetect(start.Add(time.Duration(i) * 40 * time.Millisecond)) {
+			ds.Observe(20 * time.Millisecond)
+		}
+	}
+	if ds.Interval() != 2 {
+		t.Errorf("expected interval to return to 2, got %d", ds.Interval())
+	}
+}
+
+func TestDetectionSchedulerAdaptsFPS(t *testing.T) {
+	ds := newTestScheduler(t, &DetectionRateSettings{Mode: "fps", TargetFPS: 10, LatencyBudgetMs: 100, MinFPS: 2})
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	inferences := 0
+	for i := 0; i < 1000; i++ {
+		if ds.ShouldDetect(start.Add(time.Duration(i) * 20 * time.Millisecond)) {
+			inferences++
+			ds.Observe(300 * time.Millisecond)
+		}
+	}
+	if ds.FPS() != 2 {
+		t.Errorf("expected FPS to drop to 2, got %v", ds.FPS())
+	}
+	if ds.InferencePeriod() != 500*time.Millisecond {
+		t.Errorf("expected inference period of 500 ms, got %v", ds.InferencePeriod())
+	}
+	// 20 s at 10 FPS without adaptation
+	if inferences >= 150 {
+		t.Errorf("expected fewer inferences after adaptation, got %d", inferences)
+	}
+
+	for i := 1000; i < 3000; i++ {
+		if ds.ShouldDetect(start.Add(time.Duration(i) * 20 * time.Millisecond)) {
+			ds.Observe(20 * time.Millisecond)
+		}
+	}
+	if ds.FPS() != 10 {
+		t.Errorf("expected FPS to return to 10, got %v", ds.FPS())
+	}
+}
+
+func TestTrackerMaxAgeScalesWithInferencePeriod(t *testing.T) {
+	tracker := NewTracker(&TrackerSettings{IoUThreshold: 0.3, MaxAgeSec: 1})
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	detection := func() []*DetectedObject {
+		return []*DetectedObject{{Rect: image.Rect(10, 10, 50, 50), ClassName: "person"}}
+	}
+	tracker.Update(detection(), start)
+
+	// Inference runs every 2 s: track must survive until the next one
+	tracker.SetInferencePeriod(2 * time.Second)
+	if n := len(tracker.Predict(start.Add(1500 * time.Millisecond))); n != 1 {
+		t.Errorf("expected predicted track between inferences, got %d", n)
+	}
+	if update := tracker.Update(nil, start.Add(1900*time.Millisecond)); len(update.Lost) != 0 {
+		t.Errorf("track has been lost between inferences")
+	}
+	if update := tracker.Update(detection(), start.Add(2*time.Second)); len(update.New) != 0 || len(update.Lost) != 0 {
+		t.Errorf("expected track to continue, got %d new and %d lost", len(update.New), len(update.Lost))
+	}
+	if update := tracker.Update(nil, start.Add(6500*time.Millisecond)); len(update.Lost) != 1 {
+		t.Errorf("expected track to be lost after 2 missed inferences")
+	}
+
+	// Configured max age is kept when inference is frequent
+	tracker.SetInferencePeriod(100 * time.Millisecond)
+	tracker.Update(detection(), start.Add(10*time.Second))
+	if update := tracker.Update(nil, start.Add(11500*time.Millisecond)); len(update.Lost) != 1 {
+		t.Errorf("expected track to be lost after configured max age")
+	}
+}
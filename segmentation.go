@@ -0,0 +1,202 @@
+package ml
+
+import (
+	"image"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Number of mask coefficients of YOLO segmentation models
+const segMaskCoefficients = 32
+
+// SegmentationDetector Detector for YOLO segmentation models (YOLOv5-seg and YOLOv8-seg exported to ONNX).
+// Besides boxes it returns instance mask of every detected object
+type SegmentationDetector struct {
+	settings *NeuralNetworkSettings
+
+	mu          sync.Mutex
+	net         gocv.Net
+	layersNames []string
+}
+
+// NewSegmentationDetector Reads segmentation model from settings
+func NewSegmentationDetector(settings *NeuralNetworkSettings) (*SegmentationDetector, error) {
+	net, layersNames, err := readNet(settings.Model, "", settings.Backend, settings.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(layersNames) != 2 {
+		_ = net.Close()
+		return nil, errors.Errorf("Segmentation model must have 2 outputs, got %d", len(layersNames))
+	}
+	return &SegmentationDetector{settings: settings, net: net, layersNames: layersNames}, nil
+}
+
+// Detect implements Detector
+func (sd *SegmentationDetector) Detect(img gocv.Mat, params *DetectionParams) ([]*DetectedObject, error) {
+	inputSize := image.Pt(sd.settings.InputSize, sd.settings.InputSize)
+	blob := gocv.BlobFromImage(img, yoloScaleFactor, inputSize, yoloMean, true, false)
+	defer blob.Close()
+
+	sd.mu.Lock()
+	sd.net.SetInput(blob, "")
+	outputs := sd.net.ForwardLayers(sd.layersNames)
+	sd.mu.Unlock()
+	defer closeMats(outputs)
+
+	// Prototypes of masks are the only 4-D output: [1, 32, height, width]
+	predictions, protos := outputs[0], outputs[1]
+	if len(predictions.Size()) == 4 {
+		predictions, protos = protos, predictions
+	}
+	return sd.postprocess(predictions, protos, params, image.Pt(img.Cols(), img.Rows()))
+}
+
+// Close implements Detector
+func (sd *SegmentationDetector) Close() error {
+	return sd.net.Close()
+}
+
+func (sd *SegmentationDetector) postprocess(predictions, protos gocv.Mat, params *DetectionParams, frameSize image.Point) ([]*DetectedObject, error) {
+	dims := predictions.Size()
+	protoDims := protos.Size()
+	if len(dims) != 3 || len(protoDims) != 4 || protoDims[1] != segMaskCoefficients {
+		return nil, errors.Errorf("Unexpected shapes of segmentation outputs %v and %v", dims, protoDims)
+	}
+	data, err := predictions.DataPtrFloat32()
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't extract predictions")
+	}
+	protoData, err := protos.DataPtrFloat32()
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't extract mask prototypes")
+	}
+
+	// YOLOv8: [1, 4 + classes + 32, boxes]; YOLOv5: [1, boxes, 5 + classes + 32]
+	v8 := sd.settings.ModelType == "yolov8-seg"
+	boxes, attrs := dims[1], dims[2]
+	if v8 {
+		boxes, attrs = dims[2], dims[1]
+	}
+	at := func(box, attr int) float32 {
+		if v8 {
+			return data[attr*boxes+box]
+		}
+		return data[box*attrs+attr]
+	}
+	classesOffset := 5
+	if v8 {
+		classesOffset = 4
+	}
+	numClasses := attrs - classesOffset - segMaskCoefficients
+	if numClasses <= 0 {
+		return nil, errors.Errorf("Unexpected number of attributes %d", attrs)
+	}
+
+	sx := float64(frameSize.X) / float64(sd.settings.InputSize)
+	sy := float64(frameSize.Y) / float64(sd.settings.InputSize)
+	var objects []*DetectedObject
+	var bboxes []image.Rectangle
+	var confidences []float32
+	var coefficients [][]float32
+	for i := 0; i < boxes; i++ {
+		classID, confidence := 0, float32(0)
+		for c := 0; c < numClasses; c++ {
+			if score := at(i, classesOffset+c); score > confidence {
+				classID, confidence = c, score
+			}
+		}
+		if !v8 {
+			confidence *= at(i, 4)
+		}
+		if confidence <= params.ConfThreshold || classID >= len(params.NetClasses) {
+			continue
+		}
+		className := params.NetClasses[classID]
+		if !stringInSlice(&className, params.Filters) {
+			continue
+		}
+		cx, cy, w, h := float64(at(i, 0))*sx, float64(at(i, 1))*sy, float64(at(i, 2))*sx, float64(at(i, 3))*sy
+		rect := image.Rect(int(cx-w/2), int(cy-h/2), int(cx+w/2), int(cy+h/2))
+		coeffs := make([]float32, segMaskCoefficients)
+		for k := range coeffs {
+			coeffs[k] = at(i, classesOffset+numClasses+k)
+		}
+		objects = append(objects, &DetectedObject{Rect: rect, ClassID: classID, ClassName: className, Confidence: confidence})
+		bboxes = append(bboxes, rect)
+		confidences = append(confidences, confidence)
+		coefficients = append(coefficients, coeffs)
+	}
+
+	index := make(map[*DetectedObject]int, len(objects))
+	for i, object := range objects {
+		index[object] = i
+	}
+	kept := applyNMS(objects, bboxes, confidences, params.ConfThreshold, params.NmsThreshold)
+	proto := protoMasks{data: protoData, width: protoDims[3], height: protoDims[2]}
+	for _, object := range kept {
+		object.Mask = proto.mask(coefficients[index[object]], object.Rect, frameSize, float32(sd.settings.MaskThreshold))
+	}
+	return kept, nil
+}
+
+// protoMasks Mask prototypes of segmentation model: [32, height, width]
+type protoMasks struct {
+	data          []float32
+	width, height int
+}
+
+// mask Builds mask of object as sigmoid of linear combination of prototypes, sampled inside of box
+func (pm protoMasks) mask(coeffs []float32, rect image.Rectangle, frameSize image.Point, threshold float32) *Mask {
+	rect = rect.Intersect(image.Rectangle{Max: frameSize})
+	if rect.Empty() {
+		return nil
+	}
+	// Linear combination is computed only for prototype cells covered by box
+	px0, py0 := rect.Min.X*pm.width/frameSize.X, rect.Min.Y*pm.height/frameSize.Y
+	px1 := int(math.Ceil(float64(rect.Max.X*pm.width)/float64(frameSize.X))) - 1
+	py1 := int(math.Ceil(float64(rect.Max.Y*pm.height)/float64(frameSize.Y))) - 1
+	cw, ch := px1-px0+1, py1-py0+1
+	cells := make([]float32, cw*ch)
+	plane := pm.width * pm.height
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			offset := (py0+y)*pm.width + px0 + x
+			sum := float32(0)
+			for k, c := range coeffs {
+				sum += c * pm.data[k*plane+offset]
+			}
+			cells[y*cw+x] = sum
+		}
+	}
+
+	// sigmoid(v) > threshold <=> v > logit(threshold)
+	logit := float32(math.Log(float64(threshold) / (1 - float64(threshold))))
+	cell := func(x, y int) float32 {
+		x = min(max(x, 0), cw-1)
+		y = min(max(y, 0), ch-1)
+		return cells[y*cw+x]
+	}
+	// Cells are upsampled to box size by bilinear interpolation
+	m := &Mask{Rect: rect, Data: make([]byte, rect.Dx()*rect.Dy())}
+	for y := 0; y < rect.Dy(); y++ {
+		fy := (float64(rect.Min.Y+y)+0.5)*float64(pm.height)/float64(frameSize.Y) - 0.5 - float64(py0)
+		y0 := int(math.Floor(fy))
+		wy := float32(fy - float64(y0))
+		for x := 0; x < rect.Dx(); x++ {
+			fx := (float64(rect.Min.X+x)+0.5)*float64(pm.width)/float64(frameSize.X) - 0.5 - float64(px0)
+			x0 := int(math.Floor(fx))
+			wx := float32(fx - float64(x0))
+			top := cell(x0, y0)*(1-wx) + cell(x0+1, y0)*wx
+			bottom := cell(x0, y0+1)*(1-wx) + cell(x0+1, y0+1)*wx
+			if top*(1-wy)+bottom*wy > logit {
+				m.Data[y*rect.Dx()+x] = 1
+			}
+		}
+	}
+	m.Polygon = m.outline()
+	return m
+}
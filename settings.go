@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
+	"image"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
@@ -14,11 +16,35 @@ import (
 type AppSettings struct {
 	Leaky                      bool                        `json:"leaky"`
 	Source                     string                      `json:"source"`
+	StreamName                 string                      `json:"stream_name"`
 	NeuralNetworkSettings      NeuralNetworkSettings       `json:"neural_network_settings"`
 	CameraSettings             *CameraSettings             `json:"camera_settings"`
 	VideoCaptureDeviceSettings *VideoCaptureDeviceSettings `json:"video_capture_device"`
 	VideoSettings              *VideoSettings              `json:"video_settings"`
 	MjpegSettings              MjpegSettings               `json:"mjpeg_settings"`
+	LogSettings                LogSettings                 `json:"log_settings"`
+	EventRecorderSettings      EventRecorderSettings       `json:"event_recorder_settings"`
+	TrackerSettings            TrackerSettings             `json:"tracker_settings"`
+	SnapshotSettings           SnapshotSettings            `json:"snapshot_settings"`
+	MQTTSettings               MQTTSettings                `json:"mqtt_settings"`
+	WebhookSettings            WebhookSettings             `json:"webhook_settings"`
+	EventStoreSettings         EventStoreSettings          `json:"event_store_settings"`
+	RecordingSettings          RecordingSettings           `json:"recording_settings"`
+	HLSSettings                HLSSettings                 `json:"hls_settings"`
+	HTTPSettings               HTTPSettings                `json:"http_settings"`
+	DashboardSettings          DashboardSettings           `json:"dashboard_settings"`
+	OverlaySettings            OverlaySettings             `json:"overlay_settings"`
+	RedactionSettings          RedactionSettings           `json:"redaction_settings"`
+	MotionSettings             MotionSettings              `json:"motion_settings"`
+	DetectionRateSettings      DetectionRateSettings       `json:"detection_rate_settings"`
+	TilingSettings             TilingSettings              `json:"tiling_settings"`
+	ROISettings                ROISettings                 `json:"roi_settings"`
+	BatchSettings              BatchSettings               `json:"batch_settings"`
+	FallSettings               FallSettings                `json:"fall_settings"`
+	DatasetExportSettings      DatasetExportSettings       `json:"dataset_export_settings"`
+	Zones                      []ZoneSettings              `json:"zones"`
+
+	logger *slog.Logger
 
 	sync.RWMutex
 }
@@ -44,12 +70,64 @@ func NewSettings(fileName string) (*AppSettings, error) {
 	if settings.Source == "" {
 		return nil, fmt.Errorf("source setting is empty")
 	}
+	if settings.StreamName == "" {
+		settings.StreamName = settings.Source
+	}
+
+	// Prepare logger
+	settings.LogSettings.Prepare()
+	settings.logger, err = NewLogger(settings.LogSettings)
+	if err != nil {
+		return nil, err
+	}
 
 	// Prepare video settings
 	if settings.VideoSettings == nil {
 		return nil, fmt.Errorf("field 'video_settings' has not been provided in configuration file")
 	}
-	settings.VideoSettings.Prepare()
+	settings.VideoSettings.Prepare(settings.logger)
+	settings.EventRecorderSettings.Prepare(settings.logger)
+	settings.TrackerSettings.Prepare()
+	settings.SnapshotSettings.Prepare()
+	settings.MQTTSettings.Prepare(settings.StreamName)
+	settings.EventStoreSettings.Prepare(settings.StreamName)
+	settings.RecordingSettings.Prepare()
+	settings.HLSSettings.Prepare()
+	settings.MjpegSettings.Prepare()
+	settings.DashboardSettings.Prepare()
+	settings.BatchSettings.Prepare()
+	settings.FallSettings.Prepare()
+	if err := settings.HTTPSettings.Prepare(settings.MjpegSettings.Port); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'http_settings'")
+	}
+	if err := settings.WebhookSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'webhook_settings'")
+	}
+	if err := settings.OverlaySettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'overlay_settings'")
+	}
+	if err := settings.RedactionSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'redaction_settings'")
+	}
+	if err := settings.MotionSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'motion_settings'")
+	}
+	if err := settings.DetectionRateSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'detection_rate_settings'")
+	}
+	if err := settings.TilingSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'tiling_settings'")
+	}
+	if err := settings.ROISettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'roi_settings'")
+	}
+	if err := settings.DatasetExportSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'dataset_export_settings'")
+	}
+	// Redacted frames are useless for training, while unredacted copies must not be written to disk
+	if settings.DatasetExportSettings.Enable && settings.RedactionSettings.Enable {
+		return nil, fmt.Errorf("'dataset_export_settings' can't be enabled together with 'redaction_settings'")
+	}
 
 	// Prepare Darknet's classes
 	content, err := ioutil.ReadFile(settings.NeuralNetworkSettings.DarknetClasses)
@@ -57,15 +135,47 @@ func NewSettings(fileName string) (*AppSettings, error) {
 		return nil, errors.Wrap(err, "Can't read Darknet's classes file")
 	}
 	settings.NeuralNetworkSettings.NetClasses = strings.Split(string(content), "\n")
+	if err := settings.NeuralNetworkSettings.Prepare(); err != nil {
+		return nil, errors.Wrap(err, "Invalid 'neural_network_settings'")
+	}
 
 	return &settings, nil
 }
 
+// ReducedSize returns size to which frames of configured source are scaled before detection
+func (settings *AppSettings) ReducedSize() image.Point {
+	if settings.Source == "camera" {
+		return image.Pt(settings.CameraSettings.ReducedWidth, settings.CameraSettings.ReducedHeight)
+	}
+	return image.Pt(settings.VideoSettings.ReducedWidth, settings.VideoSettings.ReducedHeight)
+}
+
+// Logger returns logger configured by 'log_settings'
+func (settings *AppSettings) Logger() *slog.Logger {
+	return settings.logger
+}
+
 // MjpegSettings settings for output
 type MjpegSettings struct {
 	ImshowEnable bool `json:"imshow_enable"`
 	Enable       bool `json:"enable"`
 	Port         int  `json:"port"`
+	// JPEG quality of streamed frames
+	Quality int `json:"quality"`
+	// Maximal number of frames per second sent to every client. 0 means unlimited
+	MaxFPS float64 `json:"max_fps"`
+	// Stream source frames on /stream/raw
+	RawEnable bool `json:"raw_enable"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ms *MjpegSettings) Prepare() {
+	if ms.Quality <= 0 || ms.Quality > 100 {
+		ms.Quality = 95
+	}
+	if ms.MaxFPS < 0 {
+		ms.MaxFPS = 0
+	}
 }
 
 // CameraSettings settings for camera settings
@@ -93,7 +203,67 @@ type NeuralNetworkSettings struct {
 	DarknetClasses string  `json:"darknet_classes"`
 	ConfThreshold  float64 `json:"conf_threshold"`
 	NmsThreshold   float64 `json:"nms_threshold"`
+	// Type of model: "darknet" (default), "yolov5-seg", "yolov8-seg" or "yolov8-pose". ONNX models are read from 'model'
+	ModelType string `json:"model_type"`
+	Model     string `json:"model"`
+	// Size of square input of ONNX model
+	InputSize int `json:"input_size"`
+	// Minimal probability of pixel to belong to instance mask
+	MaskThreshold float64 `json:"mask_threshold"`
 	// Exported, but not from JSON
 	NetClasses    []string `json:"-"`
 	TargetClasses []string `json:"target_classes"`
+	// Second-stage classifiers run on crops of detected objects
+	Classifiers []ClassifierSettings `json:"classifiers"`
+}
+
+// SameNetwork Checks whether both settings load the same neural network, so single detector can be shared.
+// Thresholds and classes are not compared: they are provided to detector on every call
+func (ns *NeuralNetworkSettings) SameNetwork(other *NeuralNetworkSettings) bool {
+	return ns.ModelType == other.ModelType &&
+		ns.Model == other.Model &&
+		ns.DarknetCFG == other.DarknetCFG &&
+		ns.DarknetWeights == other.DarknetWeights &&
+		ns.Backend == other.Backend &&
+		ns.Target == other.Target &&
+		ns.InputSize == other.InputSize &&
+		ns.MaskThreshold == other.MaskThreshold
+}
+
+// Prepare prepares the structure for further usage.
+func (ns *NeuralNetworkSettings) Prepare() error {
+	if ns.ConfThreshold <= 0 || ns.ConfThreshold >= 1 {
+		ns.ConfThreshold = 0.5
+	}
+	if ns.NmsThreshold <= 0 || ns.NmsThreshold >= 1 {
+		ns.NmsThreshold = 0.4
+	}
+	switch ns.ModelType {
+	case "":
+		ns.ModelType = "darknet"
+	case "darknet":
+	case "yolov5-seg", "yolov8-seg", "yolov8-pose":
+		if ns.Model == "" {
+			return fmt.Errorf("'model' is required for model type '%s'", ns.ModelType)
+		}
+	default:
+		return fmt.Errorf("unknown model type '%s'", ns.ModelType)
+	}
+	if ns.InputSize <= 0 {
+		ns.InputSize = 640
+	}
+	if ns.MaskThreshold <= 0 || ns.MaskThreshold >= 1 {
+		ns.MaskThreshold = 0.5
+	}
+	names := make(map[string]bool, len(ns.Classifiers))
+	for i := range ns.Classifiers {
+		if err := ns.Classifiers[i].Prepare(); err != nil {
+			return err
+		}
+		if names[ns.Classifiers[i].Name] {
+			return fmt.Errorf("duplicate classifier name '%s'", ns.Classifiers[i].Name)
+		}
+		names[ns.Classifiers[i].Name] = true
+	}
+	return nil
 }
@@ -0,0 +1,23 @@
+package ml
+
+// BatchSettings Settings for batching of images of all streams into single forward pass of neural network
+type BatchSettings struct {
+	Enable bool `json:"enable"`
+	// Maximal number of images in forward pass
+	MaxBatchSize int `json:"max_batch_size"`
+	// Maximal time of waiting for more images after the first one
+	MaxWaitMs float64 `json:"max_wait_ms"`
+}
+
+// Prepare prepares the structure for further usage.
+func (bs *BatchSettings) Prepare() {
+	if bs.MaxBatchSize <= 0 {
+		bs.MaxBatchSize = 4
+	}
+	if bs.MaxWaitMs < 0 {
+		bs.MaxWaitMs = 0
+	}
+	if bs.MaxWaitMs == 0 && bs.Enable {
+		bs.MaxWaitMs = 10
+	}
+}
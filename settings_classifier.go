@@ -0,0 +1,71 @@
+package ml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ClassifierSettings Settings for second-stage classifier run on crops of detected objects
+type ClassifierSettings struct {
+	// Name of attribute attached to detected objects
+	Name string `json:"name"`
+	// Model file (ONNX, Caffe, TensorFlow, Darknet...) and optional configuration file
+	Model  string `json:"model"`
+	Config string `json:"config"`
+	// Labels of classifier outputs. Either inline or from file with label per line
+	Labels     []string `json:"labels"`
+	LabelsFile string   `json:"labels_file"`
+	// Classes of detected objects passed to classifier. Empty means all classes
+	Classes []string `json:"classes"`
+	// Input of classifier
+	InputWidth  int        `json:"input_width"`
+	InputHeight int        `json:"input_height"`
+	Scale       float64    `json:"scale"`
+	Mean        [3]float64 `json:"mean"`
+	SwapRB      bool       `json:"swap_rb"`
+	// Apply softmax to outputs (for models returning logits)
+	Softmax bool `json:"softmax"`
+	// Attribute is attached only when confidence is above threshold
+	MinConfidence float64 `json:"min_confidence"`
+	// Crops smaller than this size (in pixels) are not classified
+	MinSize int `json:"min_size"`
+}
+
+// Prepare prepares the structure for further usage.
+func (cs *ClassifierSettings) Prepare() error {
+	if cs.Name == "" {
+		return fmt.Errorf("classifier name is empty")
+	}
+	if cs.Model == "" {
+		return fmt.Errorf("model of classifier '%s' is empty", cs.Name)
+	}
+	if len(cs.Labels) == 0 && cs.LabelsFile != "" {
+		content, err := os.ReadFile(cs.LabelsFile)
+		if err != nil {
+			return errors.Wrapf(err, "Can't read labels of classifier '%s'", cs.Name)
+		}
+		cs.Labels = strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+	if len(cs.Labels) == 0 {
+		return fmt.Errorf("labels of classifier '%s' are empty", cs.Name)
+	}
+	if cs.InputWidth <= 0 {
+		cs.InputWidth = 224
+	}
+	if cs.InputHeight <= 0 {
+		cs.InputHeight = 224
+	}
+	if cs.Scale <= 0 {
+		cs.Scale = 1.0 / 255.0
+	}
+	if cs.MinConfidence < 0 || cs.MinConfidence >= 1 {
+		cs.MinConfidence = 0
+	}
+	if cs.MinSize <= 0 {
+		cs.MinSize = 16
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package ml
+
+// DashboardSettings Settings for embedded web dashboard and its JSON API
+type DashboardSettings struct {
+	Enable bool `json:"enable"`
+	// Role required for changing runtime configuration. Changes are only possible when authentication is enabled
+	AdminRole string `json:"admin_role"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ds *DashboardSettings) Prepare() {
+	if ds.AdminRole == "" {
+		ds.AdminRole = "admin"
+	}
+}
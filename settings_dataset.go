@@ -0,0 +1,88 @@
+package ml
+
+import "fmt"
+
+// DatasetExportSettings Settings for harvesting training data from production frames (active learning)
+type DatasetExportSettings struct {
+	Enable    bool   `json:"enable"`
+	Directory string `json:"directory"`
+	// Pre-label formats: "yolo" (txt), "voc" (Pascal VOC XML) and "coco" (single JSON)
+	Formats []string `json:"formats"`
+	// Classes which trigger export. Empty list means all classes
+	Classes []string `json:"classes"`
+	// Frame is exported when confidence of detection falls in [min_confidence, max_confidence]
+	MinConfidence float64 `json:"min_confidence"`
+	MaxConfidence float64 `json:"max_confidence"`
+	// Frame is exported when track disappears and reappears at least flicker_min_gaps times within flicker_window_sec. 0 disables flicker trigger
+	FlickerMinGaps   int     `json:"flicker_min_gaps"`
+	FlickerWindowSec float64 `json:"flicker_window_sec"`
+	// Minimal time between exported frames (5 by default)
+	MinIntervalSec float64 `json:"min_interval_sec"`
+	// Maximal number of exported frames per hour (60 by default)
+	MaxPerHour int `json:"max_per_hour"`
+	// Frames with perceptual hash within this Hamming distance (of 64 bits) from recently exported ones are skipped (6 by default). -1 disables deduplication
+	HashDistance int `json:"hash_distance"`
+	// Number of recently exported hashes kept for deduplication
+	HashHistory int `json:"hash_history"`
+	JPEGQuality int `json:"jpeg_quality"`
+	// Number of frames waiting to be written. Frames are dropped when queue is full
+	QueueSize int `json:"queue_size"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ds *DatasetExportSettings) Prepare() error {
+	if ds.Directory == "" {
+		ds.Directory = "dataset"
+	}
+	if len(ds.Formats) == 0 {
+		ds.Formats = []string{"yolo"}
+	}
+	for _, format := range ds.Formats {
+		switch format {
+		case "yolo", "voc", "coco":
+		default:
+			return fmt.Errorf("unknown format '%s'", format)
+		}
+	}
+	if ds.MinConfidence <= 0 || ds.MinConfidence >= 1 {
+		ds.MinConfidence = 0.3
+	}
+	if ds.MaxConfidence <= ds.MinConfidence || ds.MaxConfidence > 1 {
+		ds.MaxConfidence = 0.6
+	}
+	if ds.FlickerMinGaps < 0 {
+		ds.FlickerMinGaps = 0
+	}
+	if ds.FlickerWindowSec <= 0 {
+		ds.FlickerWindowSec = 5
+	}
+	if ds.MinIntervalSec <= 0 {
+		ds.MinIntervalSec = 5
+	}
+	if ds.MaxPerHour <= 0 {
+		ds.MaxPerHour = 60
+	}
+	switch {
+	case ds.HashDistance < 0:
+		ds.HashDistance = -1
+	case ds.HashDistance == 0:
+		ds.HashDistance = 6
+	case ds.HashDistance > 64:
+		return fmt.Errorf("hash distance %d is above 64 bits of hash", ds.HashDistance)
+	}
+	if ds.HashHistory <= 0 {
+		ds.HashHistory = 500
+	}
+	if ds.JPEGQuality <= 0 || ds.JPEGQuality > 100 {
+		ds.JPEGQuality = 95
+	}
+	if ds.QueueSize <= 0 {
+		ds.QueueSize = 16
+	}
+	return nil
+}
+
+// HasFormat Checks whether pre-labels have to be written in given format
+func (ds *DatasetExportSettings) HasFormat(format string) bool {
+	return stringInSlice(&format, ds.Formats)
+}
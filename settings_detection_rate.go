@@ -0,0 +1,47 @@
+package ml
+
+import "fmt"
+
+// DetectionRateSettings Settings for running neural network on a subset of frames.
+// Boxes on skipped frames are predicted by tracker
+type DetectionRateSettings struct {
+	Enable bool `json:"enable"`
+	// Scheduling mode: "interval" (every N-th frame) or "fps" (at most 'target_fps' inferences per second)
+	Mode string `json:"mode"`
+	// Run inference on every N-th frame
+	Interval int `json:"interval"`
+	// Maximal number of inferences per second
+	TargetFPS float64 `json:"target_fps"`
+	// Average end-to-end latency of frames passed to neural network. When it is exceeded, interval is increased up to 'max_interval'
+	// ("interval" mode) or FPS is decreased down to 'min_fps' ("fps" mode). 0 disables adaptation
+	LatencyBudgetMs float64 `json:"latency_budget_ms"`
+	MaxInterval     int     `json:"max_interval"`
+	MinFPS          float64 `json:"min_fps"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ds *DetectionRateSettings) Prepare() error {
+	switch ds.Mode {
+	case "":
+		ds.Mode = "interval"
+	case "interval", "fps":
+	default:
+		return fmt.Errorf("unknown detection rate mode '%s'", ds.Mode)
+	}
+	if ds.Interval <= 0 {
+		ds.Interval = 1
+	}
+	if ds.Mode == "fps" && ds.TargetFPS <= 0 {
+		return fmt.Errorf("'target_fps' must be positive in 'fps' mode")
+	}
+	if ds.LatencyBudgetMs < 0 {
+		ds.LatencyBudgetMs = 0
+	}
+	if ds.MaxInterval < ds.Interval {
+		ds.MaxInterval = 10 * ds.Interval
+	}
+	if ds.MinFPS <= 0 || ds.MinFPS > ds.TargetFPS {
+		ds.MinFPS = ds.TargetFPS / 10
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package ml
+
+import "log/slog"
+
+// EventRecorderSettings Settings for recording of video clips on detection events
+type EventRecorderSettings struct {
+	Enable    bool   `json:"enable"`
+	Directory string `json:"directory"`
+	// Source of recorded frames: "annotated" (scaled frame with overlay) or "raw" (source frame)
+	Source string `json:"source"`
+	// Seconds of video kept in memory and written before the first detection
+	PreRollSec float64 `json:"pre_roll_sec"`
+	// Seconds of video written after the last detection
+	PostRollSec float64 `json:"post_roll_sec"`
+	// Maximum total size of clips and sidecars of the stream. Oldest clips are removed first, the just finished one is always kept
+	MaxSizeMB int `json:"max_size_mb"`
+	// Classes which start recording. Empty list means any detected class
+	TriggerClasses []string `json:"trigger_classes"`
+	Codec          string   `json:"codec"`
+	FPS            float64  `json:"fps"`
+	// JPEG quality of frames kept in pre-roll buffer
+	BufferQuality int `json:"buffer_quality"`
+}
+
+// Prepare prepares the structure for further usage.
+func (es *EventRecorderSettings) Prepare(logger *slog.Logger) {
+	if es.Directory == "" {
+		es.Directory = "events"
+	}
+	if es.Source != "raw" {
+		es.Source = "annotated"
+	}
+	if es.PreRollSec < 0 {
+		es.PreRollSec = 0
+	}
+	if es.PostRollSec <= 0 {
+		es.PostRollSec = 5
+		logger.Warn("Field in 'event_recorder_settings' has not been provided (or <=0). Using default", "field", "post_roll_sec", "default", es.PostRollSec)
+	}
+	if es.Codec == "" {
+		es.Codec = "MJPG"
+	}
+	if es.FPS <= 0 {
+		es.FPS = 15
+		logger.Warn("Field in 'event_recorder_settings' has not been provided (or <=0). Using default", "field", "fps", "default", es.FPS)
+	}
+	if es.BufferQuality <= 0 || es.BufferQuality > 100 {
+		es.BufferQuality = 80
+	}
+}
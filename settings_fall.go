@@ -0,0 +1,42 @@
+package ml
+
+// FallSettings Settings for fall detection heuristic based on keypoints of pose models
+type FallSettings struct {
+	Enable bool `json:"enable"`
+	// Angle of torso (shoulders to hips) from vertical in degrees above which person is considered lying
+	MaxTorsoAngleDeg float64 `json:"max_torso_angle_deg"`
+	// Minimal downward speed of torso in frame heights per second which precedes fall. 0 means default (0.5), negative value disables speed check
+	MinSpeed float64 `json:"min_speed"`
+	// Fast downward movement must happen within this time before person is lying
+	SpeedWindowSec float64 `json:"speed_window_sec"`
+	// Person must be lying for this time before event is emitted. 0 means default (1)
+	MinDurationSec float64 `json:"min_duration_sec"`
+	// Minimal time between fall events of the same track
+	CooldownSec float64 `json:"cooldown_sec"`
+	// Keypoints with lower confidence are ignored
+	KeypointThreshold float64 `json:"keypoint_threshold"`
+}
+
+// Prepare prepares the structure for further usage.
+func (fs *FallSettings) Prepare() {
+	if fs.MaxTorsoAngleDeg <= 0 || fs.MaxTorsoAngleDeg >= 90 {
+		fs.MaxTorsoAngleDeg = 60
+	}
+	if fs.MinSpeed == 0 {
+		fs.MinSpeed = 0.5
+	} else if fs.MinSpeed < 0 {
+		fs.MinSpeed = -1
+	}
+	if fs.SpeedWindowSec <= 0 {
+		fs.SpeedWindowSec = 1.5
+	}
+	if fs.MinDurationSec <= 0 {
+		fs.MinDurationSec = 1
+	}
+	if fs.CooldownSec <= 0 {
+		fs.CooldownSec = 30
+	}
+	if fs.KeypointThreshold <= 0 || fs.KeypointThreshold >= 1 {
+		fs.KeypointThreshold = 0.5
+	}
+}
@@ -0,0 +1,42 @@
+package ml
+
+// HLSSettings Settings for HLS output served by HTTP server
+type HLSSettings struct {
+	Enable    bool   `json:"enable"`
+	Directory string `json:"directory"`
+	// Path to ffmpeg binary used for encoding
+	FFmpegPath  string  `json:"ffmpeg_path"`
+	BitrateKbps int     `json:"bitrate_kbps"`
+	FPS         float64 `json:"fps"`
+	// Target duration of single segment
+	SegmentDurationSec float64 `json:"segment_duration_sec"`
+	// Number of segments in rolling playlist
+	PlaylistSize int `json:"playlist_size"`
+	// x264 preset
+	Preset string `json:"preset"`
+}
+
+// Prepare prepares the structure for further usage.
+func (hs *HLSSettings) Prepare() {
+	if hs.Directory == "" {
+		hs.Directory = "hls"
+	}
+	if hs.FFmpegPath == "" {
+		hs.FFmpegPath = "ffmpeg"
+	}
+	if hs.BitrateKbps <= 0 {
+		hs.BitrateKbps = 1500
+	}
+	if hs.FPS <= 0 {
+		hs.FPS = 15
+	}
+	if hs.SegmentDurationSec <= 0 {
+		hs.SegmentDurationSec = 2
+	}
+	if hs.PlaylistSize <= 0 {
+		hs.PlaylistSize = 5
+	}
+	if hs.Preset == "" {
+		hs.Preset = "veryfast"
+	}
+}
@@ -0,0 +1,63 @@
+package ml
+
+import "fmt"
+
+// HTTPSettings Settings for HTTP server shared by MJPEG, snapshots, HLS and API endpoints
+type HTTPSettings struct {
+	// Port of server. 0 means 'port' of 'mjpeg_settings'
+	Port        int          `json:"port"`
+	TLSCertFile string       `json:"tls_cert_file"`
+	TLSKeyFile  string       `json:"tls_key_file"`
+	CORSOrigins []string     `json:"cors_origins"`
+	Auth        AuthSettings `json:"auth"`
+	// Expose pprof endpoints under /debug/pprof/
+	Debug bool `json:"debug"`
+}
+
+// AuthSettings Settings for authentication of HTTP requests
+type AuthSettings struct {
+	Enable bool        `json:"enable"`
+	Users  []AuthUser  `json:"users"`
+	Tokens []AuthToken `json:"tokens"`
+	// Path prefixes available without authentication
+	PublicPaths []string `json:"public_paths"`
+}
+
+// AuthUser Credentials for basic authentication
+type AuthUser struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+// AuthToken Credentials for bearer token authentication
+type AuthToken struct {
+	Name  string   `json:"name"`
+	Token string   `json:"token"`
+	Roles []string `json:"roles"`
+}
+
+// Prepare prepares the structure for further usage.
+func (hs *HTTPSettings) Prepare(mjpegPort int) error {
+	if hs.Port <= 0 {
+		hs.Port = mjpegPort
+	}
+	if (hs.TLSCertFile == "") != (hs.TLSKeyFile == "") {
+		return fmt.Errorf("both 'tls_cert_file' and 'tls_key_file' must be provided")
+	}
+	if len(hs.CORSOrigins) == 0 {
+		hs.CORSOrigins = []string{"*"}
+	}
+	if hs.Auth.Enable && len(hs.Auth.Users) == 0 && len(hs.Auth.Tokens) == 0 {
+		return fmt.Errorf("authentication is enabled, but neither 'users' nor 'tokens' are provided")
+	}
+	for i, token := range hs.Auth.Tokens {
+		if token.Token == "" {
+			return fmt.Errorf("token #%d is empty", i)
+		}
+		if token.Name == "" {
+			hs.Auth.Tokens[i].Name = fmt.Sprintf("token-%d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package ml
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// LogSettings Settings for logging.
+type LogSettings struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+	Output string `json:"output"`
+	// Minimal interval between repeated warnings of the same kind
+	RepeatIntervalSec float64 `json:"repeat_interval_sec"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ls *LogSettings) Prepare() {
+	ls.Level = strings.ToLower(ls.Level)
+	if ls.Level == "" {
+		ls.Level = "info"
+	}
+	ls.Format = strings.ToLower(ls.Format)
+	if ls.Format == "" {
+		ls.Format = "text"
+	}
+	if ls.Output == "" {
+		ls.Output = "stderr"
+	}
+	if ls.RepeatIntervalSec <= 0 {
+		ls.RepeatIntervalSec = 5
+	}
+}
+
+// SlogLevel returns slog.Level for configured level
+func (ls *LogSettings) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(ls.Level))
+	return level, err
+}
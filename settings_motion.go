@@ -0,0 +1,58 @@
+package ml
+
+import "fmt"
+
+// MotionSettings Settings for motion detection in front of neural network
+type MotionSettings struct {
+	Enable bool `json:"enable"`
+	// Motion detection method: "mog2" (background subtraction) or "diff" (difference of consecutive frames)
+	Method string `json:"method"`
+	// Sensitivity threshold: per-pixel intensity difference for "diff" and variance threshold for "mog2". Lower value means higher sensitivity
+	Threshold float64 `json:"threshold"`
+	// Number of frames used to build background model by "mog2"
+	History int `json:"history"`
+	// Minimal area of changed pixels relative to frame area
+	MinAreaRatio float64 `json:"min_area_ratio"`
+	// Keep running inference for some time after last motion
+	HoldSec float64 `json:"hold_sec"`
+	// Force inference when there was no inference for some time. 0 means never
+	MaxIdleSec float64 `json:"max_idle_sec"`
+	// Regions ignored by motion detector. Points of polygons are normalized to [0..1] by frame size
+	Masks [][][2]float64 `json:"masks"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ms *MotionSettings) Prepare() error {
+	switch ms.Method {
+	case "":
+		ms.Method = "mog2"
+	case "mog2", "diff":
+	default:
+		return fmt.Errorf("unknown motion detection method '%s'", ms.Method)
+	}
+	if ms.Threshold <= 0 {
+		if ms.Method == "diff" {
+			ms.Threshold = 25
+		} else {
+			ms.Threshold = 16
+		}
+	}
+	if ms.History <= 0 {
+		ms.History = 500
+	}
+	if ms.MinAreaRatio <= 0 {
+		ms.MinAreaRatio = 0.002
+	}
+	if ms.HoldSec < 0 {
+		ms.HoldSec = 0
+	}
+	if ms.MaxIdleSec < 0 {
+		ms.MaxIdleSec = 0
+	}
+	for i, mask := range ms.Masks {
+		if len(mask) < 3 {
+			return fmt.Errorf("motion mask #%d must have at least 3 points", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package ml
+
+// MQTTSettings Settings for publishing of events to MQTT broker
+type MQTTSettings struct {
+	Enable   bool   `json:"enable"`
+	Broker   string `json:"broker"`
+	ClientID string `json:"client_id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Topic of events. Supported placeholders: {stream}, {class}, {type}
+	TopicTemplate string `json:"topic_template"`
+	// Topic of retained presence state. Supported placeholders: {stream}, {zone}
+	PresenceTopicTemplate string `json:"presence_topic_template"`
+	QoS                   int    `json:"qos"`
+	// Types of published events: "detection", "track_new", "track_lost"
+	Events []string `json:"events"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ms *MQTTSettings) Prepare(streamName string) {
+	if ms.Broker == "" {
+		ms.Broker = "tcp://localhost:1883"
+	}
+	if ms.ClientID == "" {
+		ms.ClientID = "ml-" + sanitizeFileName(streamName)
+	}
+	if ms.TopicTemplate == "" {
+		ms.TopicTemplate = "ml/{stream}/{class}/{type}"
+	}
+	if ms.PresenceTopicTemplate == "" {
+		ms.PresenceTopicTemplate = "ml/{stream}/zones/{zone}/presence"
+	}
+	if ms.QoS < 0 || ms.QoS > 2 {
+		ms.QoS = 0
+	}
+	if len(ms.Events) == 0 {
+		ms.Events = []string{string(EventTrackNew), string(EventTrackLost), string(EventFall)}
+	}
+}
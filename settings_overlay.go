@@ -0,0 +1,125 @@
+package ml
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OverlaySettings Settings for drawing of detections and frame information on annotated frames
+type OverlaySettings struct {
+	// Toggles of overlay elements. Boxes and class names are drawn by default
+	Boxes      *bool `json:"boxes"`
+	ClassName  *bool `json:"class_name"`
+	Confidence *bool `json:"confidence"`
+	TrackID    *bool `json:"track_id"`
+	// Labels of second-stage classifiers
+	Attributes *bool `json:"attributes"`
+	// Translucent instance masks of segmentation models
+	Masks *bool `json:"masks"`
+	// Skeletons of pose models
+	Skeleton *bool `json:"skeleton"`
+	// Draw filled background under labels
+	LabelBackground *bool `json:"label_background"`
+	Timestamp       *bool `json:"timestamp"`
+	FPS             *bool `json:"fps"`
+	StreamName      *bool `json:"stream_name"`
+
+	Thickness int     `json:"thickness"`
+	FontScale float64 `json:"font_scale"`
+	// Opacity of instance masks
+	MaskAlpha float64 `json:"mask_alpha"`
+	// Keypoints with lower confidence are not drawn
+	KeypointThreshold float64 `json:"keypoint_threshold"`
+	// Colors of boxes per class name in '#RRGGBB' format
+	ClassColors map[string]string `json:"class_colors"`
+	// Palette for classes without color. Indexed by class ID
+	Palette []string `json:"palette"`
+	// Color of frame information text
+	TextColor string `json:"text_color"`
+
+	classColors map[string]color.RGBA
+	palette     []color.RGBA
+	textColor   color.RGBA
+}
+
+var defaultOverlayPalette = []string{"#FFFF00", "#00FF00", "#00FFFF", "#FF0000"}
+
+// Prepare prepares the structure for further usage.
+func (ovs *OverlaySettings) Prepare() error {
+	defaultBool(&ovs.Boxes, true)
+	defaultBool(&ovs.ClassName, true)
+	defaultBool(&ovs.Confidence, false)
+	defaultBool(&ovs.TrackID, false)
+	defaultBool(&ovs.Attributes, false)
+	defaultBool(&ovs.Masks, true)
+	defaultBool(&ovs.Skeleton, true)
+	defaultBool(&ovs.LabelBackground, false)
+	defaultBool(&ovs.Timestamp, false)
+	defaultBool(&ovs.FPS, false)
+	defaultBool(&ovs.StreamName, false)
+	if ovs.Thickness <= 0 {
+		ovs.Thickness = 1
+	}
+	if ovs.FontScale <= 0 {
+		ovs.FontScale = 1.0
+	}
+	if ovs.MaskAlpha <= 0 || ovs.MaskAlpha > 1 {
+		ovs.MaskAlpha = 0.4
+	}
+	if ovs.KeypointThreshold <= 0 || ovs.KeypointThreshold >= 1 {
+		ovs.KeypointThreshold = 0.5
+	}
+	if len(ovs.Palette) == 0 {
+		ovs.Palette = defaultOverlayPalette
+	}
+	if ovs.TextColor == "" {
+		ovs.TextColor = "#FFFFFF"
+	}
+
+	var err error
+	ovs.palette = make([]color.RGBA, len(ovs.Palette))
+	for i, hex := range ovs.Palette {
+		if ovs.palette[i], err = parseHexColor(hex); err != nil {
+			return errors.Wrapf(err, "Invalid palette color #%d", i)
+		}
+	}
+	ovs.classColors = make(map[string]color.RGBA, len(ovs.ClassColors))
+	for class, hex := range ovs.ClassColors {
+		if ovs.classColors[class], err = parseHexColor(hex); err != nil {
+			return errors.Wrapf(err, "Invalid color of class '%s'", class)
+		}
+	}
+	if ovs.textColor, err = parseHexColor(ovs.TextColor); err != nil {
+		return errors.Wrap(err, "Invalid text color")
+	}
+	return nil
+}
+
+// ClassColor returns color of boxes for provided class
+func (ovs *OverlaySettings) ClassColor(classID int, className string) color.RGBA {
+	if c, ok := ovs.classColors[className]; ok {
+		return c
+	}
+	return ovs.palette[classID%len(ovs.palette)]
+}
+
+// parseHexColor Parses color in '#RRGGBB' format
+func parseHexColor(hex string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	if len(hex) != 7 || !strings.HasPrefix(hex, "#") {
+		return c, fmt.Errorf("color '%s' is not in '#RRGGBB' format", hex)
+	}
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, errors.Wrapf(err, "Can't parse color '%s'", hex)
+	}
+	return c, nil
+}
+
+func defaultBool(value **bool, def bool) {
+	if *value == nil {
+		*value = &def
+	}
+}
@@ -0,0 +1,49 @@
+package ml
+
+// RecordingSettings Settings for continuous recording of output to segmented video files
+type RecordingSettings struct {
+	Enable    bool   `json:"enable"`
+	Directory string `json:"directory"`
+	// FourCC code of codec (e.g. "mp4v", "avc1", "MJPG")
+	Codec string `json:"codec"`
+	// File extension defining container: "mp4" or "avi"
+	Container string  `json:"container"`
+	FPS       float64 `json:"fps"`
+	// Duration of single file
+	SegmentDurationSec float64 `json:"segment_duration_sec"`
+	// Also record source frames (native resolution, without overlay) to separate files
+	RecordRaw bool `json:"record_raw"`
+	// Maximal number of segments kept per recorded stream. 0 means unlimited
+	MaxSegments int `json:"max_segments"`
+	// Segments older than this are removed. 0 means unlimited
+	MaxAgeHours float64 `json:"max_age_hours"`
+}
+
+// Prepare prepares the structure for further usage.
+func (rs *RecordingSettings) Prepare() {
+	if rs.Directory == "" {
+		rs.Directory = "recordings"
+	}
+	if rs.Container != "avi" {
+		rs.Container = "mp4"
+	}
+	if rs.Codec == "" {
+		if rs.Container == "avi" {
+			rs.Codec = "MJPG"
+		} else {
+			rs.Codec = "mp4v"
+		}
+	}
+	if rs.FPS <= 0 {
+		rs.FPS = 15
+	}
+	if rs.SegmentDurationSec <= 0 {
+		rs.SegmentDurationSec = 300
+	}
+	if rs.MaxSegments < 0 {
+		rs.MaxSegments = 0
+	}
+	if rs.MaxAgeHours < 0 {
+		rs.MaxAgeHours = 0
+	}
+}
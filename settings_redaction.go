@@ -0,0 +1,56 @@
+package ml
+
+import "fmt"
+
+// RedactionSettings Settings for privacy redaction of detected objects on output frames
+type RedactionSettings struct {
+	Enable bool `json:"enable"`
+	// Classes to redact
+	Classes []string `json:"classes"`
+	// Redaction method: "blur", "pixelate" or "black"
+	Method string `json:"method"`
+	// Size of blur kernel relative to size of box
+	BlurRatio float64 `json:"blur_ratio"`
+	// Number of blocks along the longest side of pixelated box
+	PixelBlocks int `json:"pixel_blocks"`
+	// Extends boxes by fraction of their size on every side
+	Padding float64 `json:"padding"`
+	// Padding of boxes predicted by tracker or reused on static scene, which may lag behind moving objects.
+	// 0 means default (0.25). It is never smaller than 'padding'
+	PredictedPadding float64 `json:"predicted_padding"`
+	// Role allowed to watch unredacted stream. Unredacted stream is available only when authentication is enabled
+	UnredactedRole string `json:"unredacted_role"`
+}
+
+// Prepare prepares the structure for further usage.
+func (rs *RedactionSettings) Prepare() error {
+	switch rs.Method {
+	case "":
+		rs.Method = "blur"
+	case "blur", "pixelate", "black":
+	default:
+		return fmt.Errorf("unknown redaction method '%s'", rs.Method)
+	}
+	if rs.Enable && len(rs.Classes) == 0 {
+		return fmt.Errorf("redaction is enabled, but no classes have been provided")
+	}
+	if rs.BlurRatio <= 0 || rs.BlurRatio > 1 {
+		rs.BlurRatio = 0.5
+	}
+	if rs.PixelBlocks <= 0 {
+		rs.PixelBlocks = 8
+	}
+	if rs.Padding < 0 {
+		rs.Padding = 0
+	}
+	if rs.PredictedPadding <= 0 {
+		rs.PredictedPadding = 0.25
+	}
+	if rs.PredictedPadding < rs.Padding {
+		rs.PredictedPadding = rs.Padding
+	}
+	if rs.UnredactedRole == "" {
+		rs.UnredactedRole = "admin"
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package ml
+
+import "fmt"
+
+// ROISettings Settings for region of interest and exclusion masks of detection.
+// Points of polygons are normalized to [0..1] by frame size
+type ROISettings struct {
+	Enable bool `json:"enable"`
+	// Region of interest as rectangle [x, y, width, height]. Ignored when 'polygon' is provided
+	Rect []float64 `json:"rect"`
+	// Region of interest as polygon
+	Polygon [][2]float64 `json:"polygon"`
+	// Regions where detections are discarded (e.g. TV screens, posters)
+	ExclusionMasks [][][2]float64 `json:"exclusion_masks"`
+	// Point of bounding box checked against region of interest and masks: "bottom" (bottom-center) or "center" (default)
+	Anchor string `json:"anchor"`
+}
+
+// Prepare prepares the structure for further usage.
+func (rs *ROISettings) Prepare() error {
+	if len(rs.Polygon) == 0 && len(rs.Rect) != 0 {
+		if len(rs.Rect) != 4 {
+			return fmt.Errorf("'rect' must be [x, y, width, height]")
+		}
+		x, y, w, h := rs.Rect[0], rs.Rect[1], rs.Rect[2], rs.Rect[3]
+		rs.Polygon = [][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+	}
+	if len(rs.Polygon) != 0 && len(rs.Polygon) < 3 {
+		return fmt.Errorf("'polygon' must have at least 3 points")
+	}
+	for _, p := range rs.Polygon {
+		if p[0] < 0 || p[0] > 1 || p[1] < 0 || p[1] > 1 {
+			return fmt.Errorf("point %v of region of interest is outside of frame", p)
+		}
+	}
+	for i, mask := range rs.ExclusionMasks {
+		if len(mask) < 3 {
+			return fmt.Errorf("exclusion mask #%d must have at least 3 points", i)
+		}
+		for _, p := range mask {
+			if p[0] < 0 || p[0] > 1 || p[1] < 0 || p[1] > 1 {
+				return fmt.Errorf("point %v of exclusion mask #%d is outside of frame", p, i)
+			}
+		}
+	}
+	if rs.Anchor != "bottom" {
+		rs.Anchor = "center"
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package ml
+
+// SnapshotSettings Settings for snapshot capture and HTTP snapshot endpoints
+type SnapshotSettings struct {
+	Enable bool `json:"enable"`
+	// Image format of snapshots: "jpg" or "png"
+	Format         string `json:"format"`
+	JPEGQuality    int    `json:"jpeg_quality"`
+	PNGCompression int    `json:"png_compression"`
+	// Save crop of every new track to directory
+	SaveOnNewTrack bool   `json:"save_on_new_track"`
+	SaveFullFrame  bool   `json:"save_full_frame"`
+	Directory      string `json:"directory"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ss *SnapshotSettings) Prepare() {
+	if ss.Format != "png" {
+		ss.Format = "jpg"
+	}
+	if ss.JPEGQuality <= 0 || ss.JPEGQuality > 100 {
+		ss.JPEGQuality = 95
+	}
+	if ss.PNGCompression <= 0 || ss.PNGCompression > 9 {
+		ss.PNGCompression = 3
+	}
+	if ss.Directory == "" {
+		ss.Directory = "snapshots"
+	}
+}
@@ -0,0 +1,35 @@
+package ml
+
+// EventStoreSettings Settings for persistent storage of events
+type EventStoreSettings struct {
+	Enable bool `json:"enable"`
+	// Path of database. Every stream needs its own database: 'events_<stream>.db' by default
+	Path string `json:"path"`
+	// Types of stored events: "detection", "track_new", "track_lost"
+	Events []string `json:"events"`
+	// Events (and their thumbnails and full-frame snapshots) older than this are removed. 0 disables retention
+	RetentionDays float64 `json:"retention_days"`
+	// Interval between retention runs
+	PruneIntervalSec float64 `json:"prune_interval_sec"`
+	// Maximal number of events returned by single query
+	MaxPageSize int `json:"max_page_size"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ss *EventStoreSettings) Prepare(streamName string) {
+	if ss.Path == "" {
+		ss.Path = "events_" + sanitizeFileName(streamName) + ".db"
+	}
+	if len(ss.Events) == 0 {
+		ss.Events = []string{string(EventTrackNew), string(EventTrackLost), string(EventFall)}
+	}
+	if ss.RetentionDays < 0 {
+		ss.RetentionDays = 0
+	}
+	if ss.PruneIntervalSec <= 0 {
+		ss.PruneIntervalSec = 3600
+	}
+	if ss.MaxPageSize <= 0 {
+		ss.MaxPageSize = 500
+	}
+}
@@ -0,0 +1,42 @@
+package ml
+
+import "fmt"
+
+// TilingSettings Settings for tiled inference on source frames. Helps to detect small objects on high-resolution frames
+type TilingSettings struct {
+	Enable     bool `json:"enable"`
+	TileWidth  int  `json:"tile_width"`
+	TileHeight int  `json:"tile_height"`
+	// Overlap of neighbour tiles relative to tile size
+	Overlap float64 `json:"overlap"`
+	// Run additional inference on whole frame for objects bigger than tile
+	FullFrame bool `json:"full_frame"`
+	// Metric of box overlap used for merging detections across tiles: "iou" (intersection over union) or "ios" (intersection over smaller box)
+	MergeMetric string `json:"merge_metric"`
+	// Boxes of the same class with overlap above threshold are merged
+	MergeThreshold float64 `json:"merge_threshold"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ts *TilingSettings) Prepare() error {
+	if ts.TileWidth <= 0 {
+		ts.TileWidth = yoloWidth
+	}
+	if ts.TileHeight <= 0 {
+		ts.TileHeight = yoloHeight
+	}
+	if ts.Overlap < 0 || ts.Overlap >= 1 {
+		ts.Overlap = 0.2
+	}
+	switch ts.MergeMetric {
+	case "":
+		ts.MergeMetric = "ios"
+	case "iou", "ios":
+	default:
+		return fmt.Errorf("unknown merge metric '%s'", ts.MergeMetric)
+	}
+	if ts.MergeThreshold <= 0 || ts.MergeThreshold >= 1 {
+		ts.MergeThreshold = 0.6
+	}
+	return nil
+}
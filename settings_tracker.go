@@ -0,0 +1,19 @@
+package ml
+
+// TrackerSettings Settings for tracking of detected objects across frames
+type TrackerSettings struct {
+	// Minimal intersection over union for matching detection with existing track
+	IoUThreshold float64 `json:"iou_threshold"`
+	// Seconds after which track without matched detections is considered lost
+	MaxAgeSec float64 `json:"max_age_sec"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ts *TrackerSettings) Prepare() {
+	if ts.IoUThreshold <= 0 || ts.IoUThreshold >= 1 {
+		ts.IoUThreshold = 0.3
+	}
+	if ts.MaxAgeSec <= 0 {
+		ts.MaxAgeSec = 1
+	}
+}
@@ -1,6 +1,6 @@
 package ml
 
-import "fmt"
+import "log/slog"
 
 // VideoSettings Settings for video.
 type VideoSettings struct {
@@ -16,30 +16,30 @@ type VideoSettings struct {
 }
 
 // Prepare prepares the structure for further usage.
-func (vs *VideoSettings) Prepare() {
+func (vs *VideoSettings) Prepare(logger *slog.Logger) {
 	if vs.Width <= 0 {
 		vs.Width = 640
-		fmt.Println("[WARNING] Field 'width' in 'video_settings' has not been provided (or <=0). Using default 640 width")
+		logger.Warn("Field in 'video_settings' has not been provided (or <=0). Using default", "field", "width", "default", vs.Width)
 	}
 	if vs.Height <= 0 {
 		vs.Height = 360
-		fmt.Println("[WARNING] Field 'height' in 'video_settings' has not been provided (or <=0). Using default 360 height")
+		logger.Warn("Field in 'video_settings' has not been provided (or <=0). Using default", "field", "height", "default", vs.Height)
 	}
 	if vs.ReducedWidth <= 0 {
 		vs.ReducedWidth = vs.Width
-		fmt.Println("[WARNING] Field 'reduced_width' in 'video_settings' has not been provided (or <=0). Using default reduced_width = width")
+		logger.Warn("Field in 'video_settings' has not been provided (or <=0). Using default reduced_width = width", "field", "reduced_width", "default", vs.ReducedWidth)
 	}
 	if vs.ReducedHeight <= 0 {
 		vs.ReducedHeight = vs.Height
-		fmt.Println("[WARNING] Field 'reduced_height' in 'video_settings' has not been provided (or <=0). Using default reduced_height = height")
+		logger.Warn("Field in 'video_settings' has not been provided (or <=0). Using default reduced_height = height", "field", "reduced_height", "default", vs.ReducedHeight)
 	}
 	if vs.ReducedWidth > vs.Width {
 		vs.ReducedWidth = vs.Width
-		fmt.Println("[WARNING] Field 'reduced_width' in 'video_settings' > 'width'. Using default reduced_width = width")
+		logger.Warn("Field 'reduced_width' in 'video_settings' > 'width'. Using default reduced_width = width", "field", "reduced_width", "default", vs.ReducedWidth)
 	}
 	if vs.ReducedHeight > vs.Height {
 		vs.ReducedHeight = vs.Height
-		fmt.Println("[WARNING] Field 'reduced_height' in 'video_settings' > 'height'. Using default reduced_height = height")
+		logger.Warn("Field 'reduced_height' in 'video_settings' > 'height'. Using default reduced_height = height", "field", "reduced_height", "default", vs.ReducedHeight)
 	}
 
 	vs.ScaleX = float64(vs.Width) / float64(vs.ReducedWidth)
@@ -0,0 +1,98 @@
+package ml
+
+import "fmt"
+
+// WebhookSettings Settings for HTTP notifications
+type WebhookSettings struct {
+	Enable bool          `json:"enable"`
+	Rules  []WebhookRule `json:"rules"`
+	// Timeout of single HTTP request
+	TimeoutSec float64 `json:"timeout_sec"`
+	// Number of retries after failed delivery
+	MaxRetries int `json:"max_retries"`
+	// Delay before the first retry. It is doubled for every next retry up to 'max_backoff_sec'
+	BackoffSec    float64 `json:"backoff_sec"`
+	MaxBackoffSec float64 `json:"max_backoff_sec"`
+	// Number of notifications waiting for delivery. Notifications are dropped when queue is full
+	QueueSize int `json:"queue_size"`
+	// File where notifications which can't be delivered are appended as JSON lines
+	DeadLetterFile string `json:"dead_letter_file"`
+	// Base URL of HTTP server used in snapshot URLs (e.g. http://192.168.1.10:35678)
+	PublicURL string `json:"public_url"`
+	// Directory for snapshots referenced by URL
+	SnapshotDirectory string `json:"snapshot_directory"`
+	JPEGQuality       int    `json:"jpeg_quality"`
+	// Maximal number of snapshots kept per stream. 0 means default (1000), negative value means unlimited
+	SnapshotMaxFiles int `json:"snapshot_max_files"`
+	// Snapshots older than this are removed. 0 means default (24), negative value means unlimited
+	SnapshotMaxAgeHours float64 `json:"snapshot_max_age_hours"`
+}
+
+// WebhookRule Condition which triggers HTTP notification
+type WebhookRule struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Classes matching the rule. Empty list means any class
+	Classes       []string `json:"classes"`
+	MinConfidence float32  `json:"min_confidence"`
+	// Zones matching the rule. Empty list means whole frame
+	Zones []string `json:"zones"`
+	// Minimal interval between notifications of the rule
+	CooldownSec float64 `json:"cooldown_sec"`
+	// Snapshot attached to payload: "none", "base64" or "url"
+	Snapshot string            `json:"snapshot"`
+	Headers  map[string]string `json:"headers"`
+	// Key of HMAC-SHA256 signature of body sent in 'X-Signature-256' header as "sha256=<hex>". Empty means unsigned
+	Secret string `json:"secret"`
+}
+
+// Prepare prepares the structure for further usage.
+func (ws *WebhookSettings) Prepare() error {
+	if ws.TimeoutSec <= 0 {
+		ws.TimeoutSec = 5
+	}
+	if ws.MaxRetries < 0 {
+		ws.MaxRetries = 0
+	}
+	if ws.BackoffSec <= 0 {
+		ws.BackoffSec = 1
+	}
+	if ws.MaxBackoffSec < ws.BackoffSec {
+		ws.MaxBackoffSec = 30
+	}
+	if ws.QueueSize <= 0 {
+		ws.QueueSize = 100
+	}
+	if ws.DeadLetterFile == "" {
+		ws.DeadLetterFile = "webhooks_dead_letter.jsonl"
+	}
+	if ws.SnapshotDirectory == "" {
+		ws.SnapshotDirectory = "webhook_snapshots"
+	}
+	if ws.JPEGQuality <= 0 || ws.JPEGQuality > 100 {
+		ws.JPEGQuality = 90
+	}
+	if ws.SnapshotMaxFiles == 0 {
+		ws.SnapshotMaxFiles = 1000
+	}
+	if ws.SnapshotMaxAgeHours == 0 {
+		ws.SnapshotMaxAgeHours = 24
+	}
+	for i := range ws.Rules {
+		rule := &ws.Rules[i]
+		if rule.URL == "" {
+			return fmt.Errorf("field 'url' of webhook rule #%d is empty", i)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		switch rule.Snapshot {
+		case "", "none":
+			rule.Snapshot = "none"
+		case "base64", "url":
+		default:
+			return fmt.Errorf("unknown snapshot mode '%s' of webhook rule '%s'", rule.Snapshot, rule.Name)
+		}
+	}
+	return nil
+}
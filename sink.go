@@ -0,0 +1,10 @@
+package ml
+
+// FrameSink Consumer of processed frames
+type FrameSink interface {
+	// Consume Handles processed frame and objects detected on it.
+	// Implementations must not keep references to frame's Mats after return
+	Consume(frame *FrameData, detected []*DetectedObject) error
+	// Close Flushes pending output and frees memory
+	Close() error
+}
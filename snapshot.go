@@ -0,0 +1,256 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Seconds after which track which hasn't been seen is forgotten by Snapshotter
+const snapshotTrackTTL = 60
+
+// Snapshotter Keeps the latest frames for HTTP snapshot endpoints and saves snapshots of new tracks
+type Snapshotter struct {
+	settings   *SnapshotSettings
+	streamName string
+	logger     *slog.Logger
+
+	mu        sync.RWMutex
+	raw       gocv.Mat
+	annotated gocv.Mat
+	detected  []DetectedObject
+	timestamp time.Time
+
+	// Track identifiers which have been saved already with time of last appearance
+	seen map[int]time.Time
+	// Paths to saved crops by track identifier
+	saved map[int]string
+	// Paths to saved full frames by track identifier
+	savedFrames map[int]string
+}
+
+// NewSnapshotter Creates Snapshotter. Directory for saved snapshots is created when saving is enabled
+func NewSnapshotter(settings *SnapshotSettings, streamName string, logger *slog.Logger) (*Snapshotter, error) {
+	if settings.SaveOnNewTrack {
+		if err := os.MkdirAll(settings.Directory, 0o755); err != nil {
+			return nil, errors.Wrapf(err, "Can't create snapshots directory %s", settings.Directory)
+		}
+	}
+	return &Snapshotter{
+		settings:    settings,
+		streamName:  streamName,
+		logger:      logger.With("component", "snapshot"),
+		raw:         gocv.NewMat(),
+		annotated:   gocv.NewMat(),
+		seen:        make(map[int]time.Time),
+		saved:       make(map[int]string),
+		savedFrames: make(map[int]string),
+	}, nil
+}
+
+// Consume implements FrameSink
+func (s *Snapshotter) Consume(frame *FrameData, detected []*DetectedObject) error {
+	s.mu.Lock()
+	frame.ImgSource.CopyTo(&s.raw)
+	frame.ImgScaled.CopyTo(&s.annotated)
+	s.detected = s.detected[:0]
+	for _, detection := range detected {
+		s.detected = append(s.detected, *detection)
+	}
+	s.timestamp = frame.Timestamp
+	s.mu.Unlock()
+
+	if !s.settings.SaveOnNewTrack {
+		return nil
+	}
+	for _, detection := range detected {
+		if detection.TrackID == 0 {
+			continue
+		}
+		if _, ok := s.seen[detection.TrackID]; !ok {
+			if err := s.save(frame, detection); err != nil {
+				s.logger.Error("Can't save snapshot of new track", "track_id", detection.TrackID, "error", err)
+			}
+		}
+		s.seen[detection.TrackID] = frame.Timestamp
+	}
+	for id, lastSeen := range s.seen {
+		if frame.Timestamp.Sub(lastSeen).Seconds() > snapshotTrackTTL {
+			delete(s.seen, id)
+			delete(s.saved, id)
+			delete(s.savedFrames, id)
+		}
+	}
+	return nil
+}
+
+// Close implements FrameSink
+func (s *Snapshotter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.raw.Close()
+	return s.annotated.Close()
+}
+
+// SavedPath returns path to saved crop of track (empty string if crop hasn't been saved)
+func (s *Snapshotter) SavedPath(trackID int) string {
+	return s.saved[trackID]
+}
+
+// SavedFramePath returns path to saved full frame of track (empty string if frame hasn't been saved)
+func (s *Snapshotter) SavedFramePath(trackID int) string {
+	return s.savedFrames[trackID]
+}
+
+// RegisterRoutes Registers snapshot endpoints on router
+func (s *Snapshotter) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/snapshot/raw", s.serveFrame(func() gocv.Mat { return s.raw })).Methods(http.MethodGet)
+	router.HandleFunc("/snapshot/annotated", s.serveFrame(func() gocv.Mat { return s.annotated })).Methods(http.MethodGet)
+	router.HandleFunc("/snapshot/detections", s.serveDetections).Methods(http.MethodGet)
+	router.HandleFunc("/snapshot/detections/{index:[0-9]+}", s.serveCrop).Methods(http.MethodGet)
+}
+
+func (s *Snapshotter) save(frame *FrameData, detection *DetectedObject) error {
+	name := fmt.Sprintf("%s_%s_track%d", sanitizeFileName(s.streamName), frame.Timestamp.Format("20060102T150405.000"), detection.TrackID)
+
+	crop, err := cropSource(frame.ImgSource, frame.ImgScaled, detection.Rect)
+	if err != nil {
+		return err
+	}
+	data, err := s.encode(crop, s.settings.Format)
+	crop.Close()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.settings.Directory, name+"_crop."+s.settings.Format)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrap(err, "Can't write crop")
+	}
+	s.saved[detection.TrackID] = path
+
+	if s.settings.SaveFullFrame {
+		data, err := s.encode(frame.ImgSource, s.settings.Format)
+		if err != nil {
+			return err
+		}
+		framePath := filepath.Join(s.settings.Directory, name+"_frame."+s.settings.Format)
+		if err := os.WriteFile(framePath, data, 0o644); err != nil {
+			return errors.Wrap(err, "Can't write frame")
+		}
+		s.savedFrames[detection.TrackID] = framePath
+	}
+	s.logger.Debug("Snapshot of new track has been saved", "track_id", detection.TrackID, "class", detection.ClassName, "file", path)
+	return nil
+}
+
+func (s *Snapshotter) encode(img gocv.Mat, format string) ([]byte, error) {
+	if format == "png" {
+		return EncodeImage(gocv.PNGFileExt, img, gocv.IMWritePngCompression, s.settings.PNGCompression)
+	}
+	return EncodeImage(gocv.JPEGFileExt, img, gocv.IMWriteJpegQuality, s.settings.JPEGQuality)
+}
+
+// writeImage Encodes image to format requested by 'format' query parameter and writes it to response
+func (s *Snapshotter) writeImage(w http.ResponseWriter, r *http.Request, img gocv.Mat) {
+	format := r.URL.Query().Get("format")
+	if format != "png" && format != "jpg" {
+		format = s.settings.Format
+	}
+	data, err := s.encode(img, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if format == "png" {
+		w.Header().Set("Content-Type", "image/png")
+	} else {
+		w.Header().Set("Content-Type", "image/jpeg")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(data)
+}
+
+func (s *Snapshotter) serveFrame(get func() gocv.Mat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		img := get()
+		if img.Empty() {
+			s.mu.RUnlock()
+			http.Error(w, "no frame available yet", http.StatusServiceUnavailable)
+			return
+		}
+		img = img.Clone()
+		s.mu.RUnlock()
+		defer img.Close()
+
+		s.writeImage(w, r, img)
+	}
+}
+
+func (s *Snapshotter) serveDetections(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	response := struct {
+		Stream    string            `json:"stream"`
+		Timestamp time.Time         `json:"timestamp"`
+		Objects   []*DetectedObject `json:"objects"`
+	}{
+		Stream:    s.streamName,
+		Timestamp: s.timestamp,
+		Objects:   make([]*DetectedObject, 0, len(s.detected)),
+	}
+	for i := range s.detected {
+		detection := s.detected[i]
+		response.Objects = append(response.Objects, &detection)
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Snapshotter) serveCrop(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	if index >= len(s.detected) {
+		s.mu.RUnlock()
+		http.Error(w, "detection not found", http.StatusNotFound)
+		return
+	}
+	crop, err := cropSource(s.raw, s.annotated, s.detected[index].Rect)
+	s.mu.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer crop.Close()
+
+	s.writeImage(w, r, crop)
+}
+
+// cropSource Copies region of source image at native resolution for rectangle given in coordinates of scaled image
+func cropSource(source, scaled gocv.Mat, rect image.Rectangle) (gocv.Mat, error) {
+	r := scaleRect(rect, image.Pt(scaled.Cols(), scaled.Rows()), image.Pt(source.Cols(), source.Rows()))
+	FixRectForOpenCV(&r, source.Cols(), source.Rows())
+	if r.Empty() {
+		return gocv.NewMat(), fmt.Errorf("empty crop region %v", r)
+	}
+	region := source.Region(r)
+	defer region.Close()
+	return region.Clone(), nil
+}
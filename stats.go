@@ -0,0 +1,117 @@
+package ml
+
+import (
+	"sync"
+	"time"
+)
+
+// Smoothing factor of exponential moving averages
+const statsSmoothing = 0.1
+
+// PipelineStats Collects statistics of processed frames
+type PipelineStats struct {
+	mu          sync.RWMutex
+	startedAt   time.Time
+	frames      uint64
+	lastFrame   time.Time
+	fps         float64
+	inferenceMs float64
+	detected    []DetectedObject
+	visible     map[string]int
+	totals      map[string]int
+}
+
+// StatsSnapshot Copy of PipelineStats
+type StatsSnapshot struct {
+	Stream      string    `json:"stream"`
+	StartedAt   time.Time `json:"started_at"`
+	UptimeSec   float64   `json:"uptime_sec"`
+	Frames      uint64    `json:"frames"`
+	FPS         float64   `json:"fps"`
+	InferenceMs float64   `json:"inference_ms"`
+	// Number of currently detected objects per class
+	Visible map[string]int `json:"visible"`
+	// Number of tracks started since start per class
+	Totals map[string]int `json:"totals"`
+}
+
+// NewPipelineStats Creates empty PipelineStats
+func NewPipelineStats() *PipelineStats {
+	return &PipelineStats{
+		startedAt: time.Now(),
+		visible:   make(map[string]int),
+		totals:    make(map[string]int),
+	}
+}
+
+// ObserveFrame Updates statistics with processed frame
+func (ps *PipelineStats) ObserveFrame(timestamp time.Time, inference time.Duration, detected []*DetectedObject, newTracks []*Track) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.frames++
+	if !ps.lastFrame.IsZero() {
+		if dt := timestamp.Sub(ps.lastFrame).Seconds(); dt > 0 {
+			ps.fps = ema(ps.fps, 1/dt)
+		}
+	}
+	ps.lastFrame = timestamp
+	if inference > 0 {
+		ps.inferenceMs = ema(ps.inferenceMs, float64(inference)/float64(time.Millisecond))
+	}
+
+	ps.detected = ps.detected[:0]
+	for class := range ps.visible {
+		ps.visible[class] = 0
+	}
+	for _, detection := range detected {
+		ps.detected = append(ps.detected, *detection)
+		ps.visible[detection.ClassName]++
+	}
+	for _, track := range newTracks {
+		ps.totals[track.ClassName]++
+	}
+}
+
+// Snapshot returns copy of current statistics
+func (ps *PipelineStats) Snapshot() StatsSnapshot {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	snapshot := StatsSnapshot{
+		StartedAt:   ps.startedAt,
+		UptimeSec:   time.Since(ps.startedAt).Seconds(),
+		Frames:      ps.frames,
+		FPS:         ps.fps,
+		InferenceMs: ps.inferenceMs,
+		Visible:     make(map[string]int, len(ps.visible)),
+		Totals:      make(map[string]int, len(ps.totals)),
+	}
+	for class, n := range ps.visible {
+		snapshot.Visible[class] = n
+	}
+	for class, n := range ps.totals {
+		snapshot.Totals[class] = n
+	}
+	return snapshot
+}
+
+// Detections returns copy of objects detected on the last frame and time of the frame
+func (ps *PipelineStats) Detections() ([]*DetectedObject, time.Time) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	detected := make([]*DetectedObject, 0, len(ps.detected))
+	for i := range ps.detected {
+		detection := ps.detected[i]
+		detected = append(detected, &detection)
+	}
+	return detected, ps.lastFrame
+}
+
+func ema(current, value float64) float64 {
+	if current == 0 {
+		return value
+	}
+	return current + statsSmoothing*(value-current)
+}
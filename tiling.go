@@ -0,0 +1,117 @@
+package ml
+
+import (
+	"image"
+	"sort"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// DetectObjectsTiled Detects objects on overlapping tiles of provided image (and optionally on whole image).
+// Boxes are returned in coordinates of image, detections of the same object on different tiles are merged
+func DetectObjectsTiled(app *Application, img gocv.Mat, settings *TilingSettings, netClasses []string, filters ...string) ([]*DetectedObject, error) {
+	frameSize := image.Pt(img.Cols(), img.Rows())
+	tiles := tileRects(frameSize, image.Pt(settings.TileWidth, settings.TileHeight), settings.Overlap)
+	if settings.FullFrame && len(tiles) > 1 {
+		tiles = append(tiles, image.Rectangle{Max: frameSize})
+	}
+
+	// Tiles are passed to detector concurrently, so batching detector can process them in single forward pass
+	results := make([][]*DetectedObject, len(tiles))
+	errs := make([]error, len(tiles))
+	var wg sync.WaitGroup
+	for i := range tiles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			region := img.Region(tiles[i])
+			defer region.Close()
+			results[i], errs[i] = DetectObjects(app, region, netClasses, filters...)
+		}(i)
+	}
+	wg.Wait()
+
+	var detected []*DetectedObject
+	for i, objects := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, object := range objects {
+			object.Translate(tiles[i].Min)
+		}
+		detected = append(detected, objects...)
+	}
+	return mergeDetections(detected, settings.MergeMetric, settings.MergeThreshold), nil
+}
+
+// tileRects Splits frame into tiles overlapping by fraction of tile size. Last tiles of rows and columns are aligned to frame edges
+func tileRects(frameSize, tileSize image.Point, overlap float64) []image.Rectangle {
+	xs := tileOffsets(frameSize.X, tileSize.X, overlap)
+	ys := tileOffsets(frameSize.Y, tileSize.Y, overlap)
+	tiles := make([]image.Rectangle, 0, len(xs)*len(ys))
+	for _, y := range ys {
+		for _, x := range xs {
+			tile := image.Rect(x, y, x+tileSize.X, y+tileSize.Y)
+			tiles = append(tiles, tile.Intersect(image.Rectangle{Max: frameSize}))
+		}
+	}
+	return tiles
+}
+
+func tileOffsets(length, tile int, overlap float64) []int {
+	if length <= tile {
+		return []int{0}
+	}
+	stride := int(float64(tile) * (1 - overlap))
+	if stride <= 0 {
+		stride = 1
+	}
+	var offsets []int
+	for offset := 0; offset+tile < length; offset += stride {
+		offsets = append(offsets, offset)
+	}
+	return append(offsets, length-tile)
+}
+
+// mergeDetections Greedy non-maximum suppression per class. Metric is "iou" or "ios" (intersection over smaller box)
+func mergeDetections(detected []*DetectedObject, metric string, threshold float64) []*DetectedObject {
+	sort.SliceStable(detected, func(i, j int) bool { return detected[i].Confidence > detected[j].Confidence })
+	merged := make([]*DetectedObject, 0, len(detected))
+	for _, candidate := range detected {
+		suppressed := false
+		for _, kept := range merged {
+			if kept.ClassID != candidate.ClassID {
+				continue
+			}
+			overlap := IoU(kept.Rect, candidate.Rect)
+			if metric == "ios" {
+				overlap = IoS(kept.Rect, candidate.Rect)
+			}
+			if overlap > threshold {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			merged = append(merged, candidate)
+		}
+	}
+	return merged
+}
+
+// IoS returns area of intersection of two rectangles divided by area of the smaller one
+func IoS(a, b image.Rectangle) float64 {
+	inter := a.Intersect(b)
+	if inter.Empty() {
+		return 0
+	}
+	smaller := a.Dx() * a.Dy()
+	if area := b.Dx() * b.Dy(); area < smaller {
+		smaller = area
+	}
+	if smaller <= 0 {
+		return 0
+	}
+	return float64(inter.Dx()*inter.Dy()) / float64(smaller)
+}
@@ -0,0 +1,119 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestTileOffsets(t *testing.T) {
+	tests := []struct {
+		length, tile int
+		overlap      float64
+		expected     []int
+	}{
+		// Last tile is aligned to frame edge instead of going beyond it
+		{1000, 416, 0.2, []int{0, 332, 584}},
+		{300, 100, 0, []int{0, 100, 200}},
+		{101, 100, 0.2, []int{0, 1}},
+		// Frame isn't bigger than tile
+		{416, 416, 0.2, []int{0}},
+		{300, 416, 0.2, []int{0}},
+	}
+	for _, test := range tests {
+		offsets := tileOffsets(test.length, test.tile, test.overlap)
+		if fmt.Sprint(offsets) != fmt.Sprint(test.expected) {
+			t.Errorf("length %d, tile %d, overlap %v: expected %v, got %v", test.length, test.tile, test.overlap, test.expected, offsets)
+		}
+	}
+}
+
+func TestTileOffsetsOverlapNearOne(t *testing.T) {
+	// Stride can't be zero
+	offsets := tileOffsets(250, 100, 0.999)
+	if len(offsets) != 151 {
+		t.Fatalf("expected tiles with stride 1, got %d tiles", len(offsets))
+	}
+	for i, offset := range offsets {
+		if offset != i {
+			t.Fatalf("expected offset %d, got %d", i, offset)
+		}
+	}
+}
+
+func TestTileRects(t *testing.T) {
+	frame := image.Rect(0, 0, 1000, 500)
+	tiles := tileRects(frame.Max, image.Pt(416, 416), 0.2)
+	if len(tiles) != 6 {
+		t.Fatalf("expected 3x2 tiles, got %v", tiles)
+	}
+	if last := tiles[len(tiles)-1]; last != image.Rect(584, 84, 1000, 500) {
+		t.Errorf("last tile must be aligned to bottom-right corner, got %v", last)
+	}
+	var union image.Rectangle
+	for _, tile := range tiles {
+		if tile.Size() != image.Pt(416, 416) || !tile.In(frame) {
+			t.Errorf("unexpected tile %v", tile)
+		}
+		union = union.Union(tile)
+	}
+	if union != frame {
+		t.Errorf("tiles cover %v instead of whole frame", union)
+	}
+
+	// Tile is clipped by small frame
+	tiles = tileRects(image.Pt(300, 200), image.Pt(416, 416), 0.2)
+	if len(tiles) != 1 || tiles[0] != image.Rect(0, 0, 300, 200) {
+		t.Errorf("expected single tile of whole frame, got %v", tiles)
+	}
+}
+
+func TestMergeDetections(t *testing.T) {
+	newDetections := func() []*DetectedObject {
+		return []*DetectedObject{
+			// Person cut by border of tile is detected partially on the left tile...
+			{Rect: image.Rect(100, 100, 150, 300), ClassID: 0, ClassName: "person", Confidence: 0.8},
+			// ...and completely on the overlapping right one
+			{Rect: image.Rect(100, 100, 200, 300), ClassID: 0, ClassName: "person", Confidence: 0.9},
+			// Other class at the same place is never merged
+			{Rect: image.Rect(100, 100, 200, 300), ClassID: 1, ClassName: "bicycle", Confidence: 0.7},
+		}
+	}
+	tests := []struct {
+		metric   string
+		expected []string
+	}{
+		// Intersection over union of partial and complete boxes is 0.5 only
+		{"iou", []string{"person 0.9", "person 0.8", "bicycle 0.7"}},
+		// Partial box is completely inside of the complete one
+		{"ios", []string{"person 0.9", "bicycle 0.7"}},
+	}
+	for _, test := range tests {
+		merged := mergeDetections(newDetections(), test.metric, 0.6)
+		got := make([]string, 0, len(merged))
+		for _, detection := range merged {
+			got = append(got, fmt.Sprintf("%s %.1f", detection.ClassName, detection.Confidence))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.metric, test.expected, got)
+		}
+	}
+}
+
+func TestIoS(t *testing.T) {
+	tests := []struct {
+		a, b     image.Rectangle
+		expected float64
+	}{
+		{image.Rect(0, 0, 10, 10), image.Rect(20, 20, 30, 30), 0},
+		{image.Rect(0, 0, 100, 100), image.Rect(10, 10, 20, 20), 1},
+		{image.Rect(10, 10, 20, 20), image.Rect(0, 0, 100, 100), 1},
+		{image.Rect(0, 0, 10, 10), image.Rect(5, 0, 25, 10), 0.5},
+		{image.Rect(0, 0, 10, 10), image.Rect(5, 5, 5, 5), 0},
+	}
+	for _, test := range tests {
+		if got := IoS(test.a, test.b); got != test.expected {
+			t.Errorf("%v and %v: expected %v, got %v", test.a, test.b, test.expected, got)
+		}
+	}
+}
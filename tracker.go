@@ -0,0 +1,197 @@
+package ml
+
+import (
+	"image"
+	"math"
+	"sort"
+	"time"
+)
+
+// Track Object followed across frames
+type Track struct {
+	ID            int
+	ClassID       int
+	ClassName     string
+	Rect          image.Rectangle
+	Confidence    float32
+	MaxConfidence float32
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	// Number of detections matched with track
+	Hits int
+	// Last known results of second-stage classifiers
+	Attributes map[string]Attribute
+
+	// Smoothed velocity of center of box in pixels per second
+	vx, vy float64
+}
+
+const (
+	// Velocity smoothing factor of tracks
+	trackVelocitySmoothing = 0.5
+	// Tracks survive this number of inferences without matched detections when inference doesn't run on every frame
+	trackMaxAgeInferences = 2
+)
+
+// TrackerUpdate Tracks which have appeared or disappeared during Tracker.Update
+type TrackerUpdate struct {
+	New  []*Track
+	Lost []*Track
+}
+
+// Tracker Greedy IoU tracker assigning persistent identifiers to detected objects
+type Tracker struct {
+	settings *TrackerSettings
+	nextID   int
+	tracks   []*Track
+	// Seconds after which unmatched track is lost. Not less than TrackerSettings.MaxAgeSec
+	maxAgeSec float64
+}
+
+// NewTracker Creates Tracker with provided settings
+func NewTracker(settings *TrackerSettings) *Tracker {
+	return &Tracker{
+		settings:  settings,
+		nextID:    1,
+		maxAgeSec: settings.MaxAgeSec,
+	}
+}
+
+// SetInferencePeriod Scales maximal age of tracks with time between inferences, so tracks aren't lost between them
+func (t *Tracker) SetInferencePeriod(period time.Duration) {
+	t.maxAgeSec = math.Max(t.settings.MaxAgeSec, trackMaxAgeInferences*period.Seconds())
+}
+
+// Update Matches detections with existing tracks and sets DetectedObject.TrackID.
+// Detections which can't be matched start new tracks, tracks which haven't been matched for too long are lost
+func (t *Tracker) Update(detected []*DetectedObject, timestamp time.Time) TrackerUpdate {
+	type candidate struct {
+		track     *Track
+		detection *DetectedObject
+		iou       float64
+	}
+	var candidates []candidate
+	for _, track := range t.tracks {
+		for _, detection := range detected {
+			if track.ClassID != detection.ClassID {
+				continue
+			}
+			if iou := IoU(track.Rect, detection.Rect); iou >= t.settings.IoUThreshold {
+				candidates = append(candidates, candidate{track: track, detection: detection, iou: iou})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].iou > candidates[j].iou })
+
+	matchedTracks := make(map[*Track]bool, len(candidates))
+	matchedDetections := make(map[*DetectedObject]bool, len(candidates))
+	for _, c := range candidates {
+		if matchedTracks[c.track] || matchedDetections[c.detection] {
+			continue
+		}
+		matchedTracks[c.track] = true
+		matchedDetections[c.detection] = true
+		c.track.observe(c.detection, timestamp)
+		c.detection.TrackID = c.track.ID
+	}
+
+	var update TrackerUpdate
+	alive := t.tracks[:0]
+	for _, track := range t.tracks {
+		if !matchedTracks[track] && timestamp.Sub(track.LastSeen).Seconds() > t.maxAgeSec {
+			update.Lost = append(update.Lost, track)
+			continue
+		}
+		alive = append(alive, track)
+	}
+	t.tracks = alive
+
+	for _, detection := range detected {
+		if matchedDetections[detection] {
+			continue
+		}
+		track := &Track{
+			ID:        t.nextID,
+			ClassID:   detection.ClassID,
+			ClassName: detection.ClassName,
+			FirstSeen: timestamp,
+		}
+		t.nextID++
+		track.observe(detection, timestamp)
+		detection.TrackID = track.ID
+		t.tracks = append(t.tracks, track)
+		update.New = append(update.New, track)
+	}
+	return update
+}
+
+// Tracks returns currently alive tracks
+func (t *Tracker) Tracks() []*Track {
+	return t.tracks
+}
+
+// Predict returns detections of alive tracks with boxes moved to provided time by velocity of tracks.
+// Used on frames which are not passed to neural network
+func (t *Tracker) Predict(timestamp time.Time) []*DetectedObject {
+	predicted := make([]*DetectedObject, 0, len(t.tracks))
+	for _, track := range t.tracks {
+		if timestamp.Sub(track.LastSeen).Seconds() > t.maxAgeSec {
+			continue
+		}
+		predicted = append(predicted, &DetectedObject{
+			Rect:       track.Predict(timestamp),
+			ClassID:    track.ClassID,
+			ClassName:  track.ClassName,
+			Confidence: track.Confidence,
+			TrackID:    track.ID,
+			Attributes: track.Attributes,
+		})
+	}
+	return predicted
+}
+
+// Predict returns box of track extrapolated to provided time
+func (track *Track) Predict(timestamp time.Time) image.Rectangle {
+	dt := timestamp.Sub(track.LastSeen).Seconds()
+	return track.Rect.Add(image.Pt(int(track.vx*dt), int(track.vy*dt)))
+}
+
+func (track *Track) observe(detection *DetectedObject, timestamp time.Time) {
+	if track.Hits != 0 {
+		if dt := timestamp.Sub(track.LastSeen).Seconds(); dt > 0 {
+			vx := float64(detection.Rect.Min.X+detection.Rect.Max.X-track.Rect.Min.X-track.Rect.Max.X) / 2 / dt
+			vy := float64(detection.Rect.Min.Y+detection.Rect.Max.Y-track.Rect.Min.Y-track.Rect.Max.Y) / 2 / dt
+			track.vx += trackVelocitySmoothing * (vx - track.vx)
+			track.vy += trackVelocitySmoothing * (vy - track.vy)
+		}
+	}
+	track.Rect = detection.Rect
+	track.Confidence = detection.Confidence
+	if len(detection.Attributes) != 0 {
+		if track.Attributes == nil {
+			track.Attributes = make(map[string]Attribute, len(detection.Attributes))
+		}
+		for name, attribute := range detection.Attributes {
+			track.Attributes[name] = attribute
+		}
+	}
+	if detection.Confidence > track.MaxConfidence {
+		track.MaxConfidence = detection.Confidence
+	}
+	track.LastSeen = timestamp
+	track.Hits++
+}
+
+// IoU returns intersection over union of two rectangles
+func IoU(a, b image.Rectangle) float64 {
+	inter := a.Intersect(b)
+	if inter.Empty() {
+		return 0
+	}
+	interArea := float64(inter.Dx() * inter.Dy())
+	union := float64(a.Dx()*a.Dy()+b.Dx()*b.Dy()) - interArea
+	if union <= 0 {
+		return 0
+	}
+	return interArea / union
+}
@@ -1,6 +1,12 @@
 package ml
 
-import "image"
+import (
+	"image"
+	"strings"
+	"unicode"
+
+	"gocv.io/x/gocv"
+)
 
 // FixRectForOpenCV Corrects rectangle's bounds for provided max-widtht and max-height
 // Helps to avoid BBox error assertion
@@ -18,3 +24,36 @@ func FixRectForOpenCV(r *image.Rectangle, maxCols, maxRows int) {
 		r.Max.Y = maxRows - 1
 	}
 }
+
+// EncodeImage Encodes image to provided format and copies result to Go's memory
+func EncodeImage(ext gocv.FileExt, img gocv.Mat, params ...int) ([]byte, error) {
+	buf, err := gocv.IMEncodeWithParams(ext, img, params)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+	return append([]byte(nil), buf.GetBytes()...), nil
+}
+
+// sanitizeFileName Replaces characters which are not safe for file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// scaleRect Maps rectangle from coordinates of frame with size 'from' to coordinates of frame with size 'to'
+func scaleRect(r image.Rectangle, from, to image.Point) image.Rectangle {
+	if from.X == 0 || from.Y == 0 {
+		return r
+	}
+	sx := float64(to.X) / float64(from.X)
+	sy := float64(to.Y) / float64(from.Y)
+	return image.Rect(
+		int(float64(r.Min.X)*sx), int(float64(r.Min.Y)*sy),
+		int(float64(r.Max.X)*sx), int(float64(r.Max.Y)*sy),
+	)
+}
@@ -0,0 +1,344 @@
+package ml
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// webhookSignatureHeader Header with HMAC-SHA256 signature of body
+const webhookSignatureHeader = "X-Signature-256"
+
+// webhookPayload Body of HTTP notification
+type webhookPayload struct {
+	Rule        string            `json:"rule"`
+	Stream      string            `json:"stream"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Objects     []*DetectedObject `json:"objects"`
+	Zones       []string          `json:"zones,omitempty"`
+	Snapshot    string            `json:"snapshot,omitempty"`
+	SnapshotURL string            `json:"snapshot_url,omitempty"`
+}
+
+// webhookDelivery Notification waiting for delivery
+type webhookDelivery struct {
+	rule    *WebhookRule
+	payload []byte
+}
+
+// deadLetter Notification which can't be delivered
+type deadLetter struct {
+	Rule     string          `json:"rule"`
+	URL      string          `json:"url"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	FailedAt time.Time       `json:"failed_at"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// WebhookNotifier Sends HTTP POST notifications when configured rules match detected objects
+type WebhookNotifier struct {
+	settings   *WebhookSettings
+	streamName string
+	zones      []*Zone
+	client     *http.Client
+	logger     *slog.Logger
+
+	lastFired map[string]time.Time
+	queue     chan *webhookDelivery
+	done      chan struct{}
+	// Closed when notifier is closing, interrupts backoff
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	deadLetterMu sync.Mutex
+}
+
+// NewWebhookNotifier Creates WebhookNotifier and starts delivery in separate goroutine
+func NewWebhookNotifier(settings *WebhookSettings, streamName string, zones []*Zone, logger *slog.Logger) (*WebhookNotifier, error) {
+	for _, rule := range settings.Rules {
+		if rule.Snapshot == "url" {
+			if err := os.MkdirAll(settings.SnapshotDirectory, 0o755); err != nil {
+				return nil, errors.Wrapf(err, "Can't create webhook snapshots directory %s", settings.SnapshotDirectory)
+			}
+			break
+		}
+	}
+	wn := &WebhookNotifier{
+		settings:   settings,
+		streamName: streamName,
+		zones:      zones,
+		client:     &http.Client{Timeout: time.Duration(settings.TimeoutSec * float64(time.Second))},
+		logger:     logger.With("component", "webhook"),
+		lastFired:  make(map[string]time.Time),
+		queue:      make(chan *webhookDelivery, settings.QueueSize),
+		done:       make(chan struct{}),
+		closing:    make(chan struct{}),
+	}
+	go wn.deliverLoop()
+	return wn, nil
+}
+
+// RegisterRoutes Registers endpoint serving snapshots referenced by URL in payloads
+func (wn *WebhookNotifier) RegisterRoutes(router *mux.Router) {
+	fs := http.StripPrefix("/webhooks/snapshots/", http.FileServer(http.Dir(wn.settings.SnapshotDirectory)))
+	router.PathPrefix("/webhooks/snapshots/").Handler(fs).Methods(http.MethodGet)
+}
+
+// Consume implements FrameSink
+func (wn *WebhookNotifier) Consume(frame *FrameData, detected []*DetectedObject) error {
+	if len(detected) == 0 {
+		return nil
+	}
+	frameSize := image.Pt(frame.ImgScaled.Cols(), frame.ImgScaled.Rows())
+	for i := range wn.settings.Rules {
+		rule := &wn.settings.Rules[i]
+		if last, ok := wn.lastFired[rule.Name]; ok && frame.Timestamp.Sub(last).Seconds() < rule.CooldownSec {
+			continue
+		}
+		matched, zones := wn.match(rule, detected, frameSize)
+		if len(matched) == 0 {
+			continue
+		}
+		wn.lastFired[rule.Name] = frame.Timestamp
+
+		payload := webhookPayload{
+			Rule:      rule.Name,
+			Stream:    wn.streamName,
+			Timestamp: frame.Timestamp,
+			Objects:   matched,
+			Zones:     zones,
+		}
+		if err := wn.attachSnapshot(rule, frame, &payload); err != nil {
+			wn.logger.Warn("Can't attach snapshot to webhook payload", "rule", rule.Name, "error", err)
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal webhook payload")
+		}
+
+		select {
+		case wn.queue <- &webhookDelivery{rule: rule, payload: data}:
+		default:
+			wn.writeDeadLetter(rule, data, 0, errors.New("delivery queue is full"))
+		}
+	}
+	return nil
+}
+
+// Close implements FrameSink. Waits until queued notifications are delivered (without further retries)
+func (wn *WebhookNotifier) Close() error {
+	wn.closeOnce.Do(func() {
+		close(wn.closing)
+		close(wn.queue)
+	})
+	<-wn.done
+	return nil
+}
+
+// match returns detections matching rule and zones they are in
+func (wn *WebhookNotifier) match(rule *WebhookRule, detected []*DetectedObject, frameSize image.Point) ([]*DetectedObject, []string) {
+	var matched []*DetectedObject
+	var matchedZones []string
+	for _, detection := range detected {
+		if len(rule.Classes) != 0 && !stringInSlice(&detection.ClassName, rule.Classes) {
+			continue
+		}
+		if detection.Confidence <= rule.MinConfidence {
+			continue
+		}
+		zones := zoneNames(wn.zones, detection.Rect, frameSize)
+		if len(rule.Zones) != 0 {
+			inZone := false
+			for i := range zones {
+				if stringInSlice(&zones[i], rule.Zones) {
+					inZone = true
+					break
+				}
+			}
+			if !inZone {
+				continue
+			}
+		}
+		matched = append(matched, detection)
+		for i := range zones {
+			if !stringInSlice(&zones[i], matchedZones) {
+				matchedZones = append(matchedZones, zones[i])
+			}
+		}
+	}
+	return matched, matchedZones
+}
+
+func (wn *WebhookNotifier) attachSnapshot(rule *WebhookRule, frame *FrameData, payload *webhookPayload) error {
+	if rule.Snapshot == "none" {
+		return nil
+	}
+	data, err := EncodeImage(gocv.JPEGFileExt, frame.ImgScaled, gocv.IMWriteJpegQuality, wn.settings.JPEGQuality)
+	if err != nil {
+		return err
+	}
+	if rule.Snapshot == "base64" {
+		payload.Snapshot = base64.StdEncoding.EncodeToString(data)
+		return nil
+	}
+
+	name := fmt.Sprintf("%s_%s_%s.jpg", sanitizeFileName(wn.streamName), sanitizeFileName(rule.Name), frame.Timestamp.Format("20060102T150405.000"))
+	if err := os.WriteFile(filepath.Join(wn.settings.SnapshotDirectory, name), data, 0o644); err != nil {
+		return err
+	}
+	payload.SnapshotURL = strings.TrimSuffix(wn.settings.PublicURL, "/") + "/webhooks/snapshots/" + name
+	wn.applyRetention()
+	return nil
+}
+
+// applyRetention Removes snapshots of stream exceeding configured count or age
+func (wn *WebhookNotifier) applyRetention() {
+	maxFiles := wn.settings.SnapshotMaxFiles
+	maxAge := time.Duration(wn.settings.SnapshotMaxAgeHours * float64(time.Hour))
+	if maxFiles < 0 && maxAge < 0 {
+		return
+	}
+	entries, err := os.ReadDir(wn.settings.SnapshotDirectory)
+	if err != nil {
+		wn.logger.Error("Can't list webhook snapshots directory", "error", err)
+		return
+	}
+	type snapshotFile struct {
+		path    string
+		modTime time.Time
+	}
+	prefix := sanitizeFileName(wn.streamName) + "_"
+	var snapshots []snapshotFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || filepath.Ext(entry.Name()) != ".jpg" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshotFile{path: filepath.Join(wn.settings.SnapshotDirectory, entry.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].modTime.Before(snapshots[j].modTime)
+	})
+
+	deadline := time.Now().Add(-maxAge)
+	for i, snapshot := range snapshots {
+		tooMany := maxFiles >= 0 && len(snapshots)-i > maxFiles
+		tooOld := maxAge >= 0 && snapshot.modTime.Before(deadline)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(snapshot.path); err != nil {
+			wn.logger.Warn("Can't remove webhook snapshot", "file", snapshot.path, "error", err)
+		}
+	}
+}
+
+func (wn *WebhookNotifier) deliverLoop() {
+	defer close(wn.done)
+	for delivery := range wn.queue {
+		attempts, err := wn.deliver(delivery)
+		if err != nil {
+			wn.writeDeadLetter(delivery.rule, delivery.payload, attempts, err)
+		}
+	}
+}
+
+// deliver Sends notification retrying with exponential backoff
+func (wn *WebhookNotifier) deliver(delivery *webhookDelivery) (int, error) {
+	var err error
+	attempt := 0
+	for ; attempt <= wn.settings.MaxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := math.Min(wn.settings.BackoffSec*math.Pow(2, float64(attempt-1)), wn.settings.MaxBackoffSec)
+			select {
+			case <-time.After(time.Duration(backoff * float64(time.Second))):
+			case <-wn.closing:
+				return attempt, errors.Wrap(err, "notifier has been closed")
+			}
+		}
+		if err = wn.post(delivery); err == nil {
+			wn.logger.Debug("Webhook has been delivered", "rule", delivery.rule.Name, "attempts", attempt+1)
+			return attempt + 1, nil
+		}
+		wn.logger.Warn("Can't deliver webhook", "rule", delivery.rule.Name, "attempt", attempt+1, "error", err)
+	}
+	return attempt, err
+}
+
+func (wn *WebhookNotifier) post(delivery *webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.rule.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range delivery.rule.Headers {
+		req.Header.Set(k, v)
+	}
+	if delivery.rule.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(delivery.rule.Secret, delivery.payload))
+	}
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// signWebhookPayload Returns "sha256=<hex>" HMAC-SHA256 signature of payload
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (wn *WebhookNotifier) writeDeadLetter(rule *WebhookRule, payload []byte, attempts int, reason error) {
+	wn.logger.Error("Webhook has been moved to dead-letter log", "rule", rule.Name, "attempts", attempts, "error", reason)
+
+	line, err := json.Marshal(deadLetter{
+		Rule:     rule.Name,
+		URL:      rule.URL,
+		Attempts: attempts,
+		Error:    reason.Error(),
+		FailedAt: time.Now(),
+		Payload:  payload,
+	})
+	if err != nil {
+		return
+	}
+
+	wn.deadLetterMu.Lock()
+	defer wn.deadLetterMu.Unlock()
+	f, err := os.OpenFile(wn.settings.DeadLetterFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		wn.logger.Error("Can't open dead-letter log", "file", wn.settings.DeadLetterFile, "error", err)
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(line, '\n'))
+}
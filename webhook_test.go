@@ -0,0 +1,288 @@
+package ml
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// webhookRequest Request received by test server
+type webhookRequest struct {
+	signature string
+	header    string
+	body      []byte
+}
+
+// newWebhookTestServer Starts server which responds with provided statuses in order and 200 afterwards
+func newWebhookTestServer(t *testing.T, statuses ...int) (*httptest.Server, func() []webhookRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{
+			signature: r.Header.Get(webhookSignatureHeader),
+			header:    r.Header.Get("X-Test"),
+			body:      body,
+		})
+		n := len(requests)
+		mu.Unlock()
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+func newTestWebhookNotifier(t *testing.T, settings *WebhookSettings, zones []*Zone) *WebhookNotifier {
+	t.Helper()
+	settings.DeadLetterFile = filepath.Join(t.TempDir(), "dead_letter.jsonl")
+	settings.SnapshotDirectory = filepath.Join(t.TempDir(), "snapshots")
+	if err := settings.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	// Keep tests fast
+	settings.BackoffSec = 0.01
+	settings.MaxBackoffSec = 0.02
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notifier, err := NewWebhookNotifier(settings, "cam", zones, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notifier
+}
+
+func newWebhookTestFrame(t *testing.T, timestamp time.Time) *FrameData {
+	t.Helper()
+	img := gocv.NewMatWithSize(100, 100, gocv.MatTypeCV8UC3)
+	t.Cleanup(func() { img.Close() })
+	return &FrameData{ImgScaled: img, Timestamp: timestamp}
+}
+
+func TestWebhookNotifierRetriesAndSigns(t *testing.T) {
+	server, requests := newWebhookTestServer(t, http.StatusInternalServerError, http.StatusBadGateway)
+	settings := &WebhookSettings{
+		MaxRetries: 3,
+		Rules: []WebhookRule{{
+			Name:    "person",
+			URL:     server.URL,
+			Classes: []string{"person"},
+			Secret:  "s3cret",
+			Headers: map[string]string{"X-Test": "value"},
+		}},
+	}
+	notifier := newTestWebhookNotifier(t, settings, nil)
+
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	detected := []*DetectedObject{{Rect: image.Rect(10, 10, 30, 60), ClassName: "person", Confidence: 0.8}}
+	if err := notifier.Consume(newWebhookTestFrame(t, timestamp), detected); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(requests()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Second call must not panic
+	if err := notifier.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := requests()
+	if len(received) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(received))
+	}
+	for i, req := range received {
+		if req.header != "value" {
+			t.Errorf("attempt %d: custom header is missing", i)
+		}
+		if !hmac.Equal([]byte(req.signature), []byte(signWebhookPayload("s3cret", req.body))) {
+			t.Errorf("attempt %d: invalid signature %s", i, req.signature)
+		}
+		if req.signature == signWebhookPayload("other", req.body) {
+			t.Errorf("attempt %d: signature doesn't depend on secret", i)
+		}
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(received[2].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Rule != "person" || payload.Stream != "cam" || !payload.Timestamp.Equal(timestamp) || len(payload.Objects) != 1 {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if _, err := os.Stat(settings.DeadLetterFile); !os.IsNotExist(err) {
+		t.Errorf("delivered webhook has been written to dead-letter log")
+	}
+}
+
+func TestWebhookNotifierDeadLetter(t *testing.T) {
+	server, requests := newWebhookTestServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	settings := &WebhookSettings{
+		MaxRetries: 1,
+		Rules:      []WebhookRule{{Name: "any", URL: server.URL}},
+	}
+	notifier := newTestWebhookNotifier(t, settings, nil)
+
+	detected := []*DetectedObject{{Rect: image.Rect(10, 10, 30, 60), ClassName: "car", Confidence: 0.5}}
+	if err := notifier.Consume(newWebhookTestFrame(t, time.Now()), detected); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(requests()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(requests()); n != 2 {
+		t.Fatalf("expected 2 attempts, got %d", n)
+	}
+	if _, err := os.Stat(settings.DeadLetterFile); err != nil {
+		t.Fatalf("dead-letter log hasn't been written: %v", err)
+	}
+	f, err := os.Open(settings.DeadLetterFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var letters []deadLetter
+	for scanner.Scan() {
+		var letter deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, letter)
+	}
+	if len(letters) != 1 || letters[0].Rule != "any" || letters[0].Attempts != 2 || letters[0].URL != server.URL {
+		t.Errorf("unexpected dead letters %+v", letters)
+	}
+}
+
+func TestWebhookRuleFiltering(t *testing.T) {
+	zones, err := NewZones([]ZoneSettings{{Name: "left", Points: [][2]float64{{0, 0}, {0.5, 0}, {0.5, 1}, {0, 1}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frameSize := image.Pt(100, 100)
+	person := &DetectedObject{Rect: image.Rect(10, 10, 30, 60), ClassName: "person", Confidence: 0.8}
+	weakPerson := &DetectedObject{Rect: image.Rect(10, 10, 30, 60), ClassName: "person", Confidence: 0.4}
+	rightPerson := &DetectedObject{Rect: image.Rect(70, 10, 90, 60), ClassName: "person", Confidence: 0.9}
+	car := &DetectedObject{Rect: image.Rect(20, 50, 40, 70), ClassName: "car", Confidence: 0.9}
+	detected := []*DetectedObject{person, weakPerson, rightPerson, car}
+
+	tests := []struct {
+		name     string
+		rule     WebhookRule
+		expected []*DetectedObject
+		zones    []string
+	}{
+		{"any", WebhookRule{}, detected, []string{"left"}},
+		{"class", WebhookRule{Classes: []string{"car"}}, []*DetectedObject{car}, []string{"left"}},
+		{"confidence", WebhookRule{Classes: []string{"person"}, MinConfidence: 0.5}, []*DetectedObject{person, rightPerson}, []string{"left"}},
+		{"zone", WebhookRule{Classes: []string{"person"}, MinConfidence: 0.5, Zones: []string{"left"}}, []*DetectedObject{person}, []string{"left"}},
+		{"nothing", WebhookRule{Classes: []string{"dog"}}, nil, nil},
+	}
+	notifier := &WebhookNotifier{zones: zones}
+	for _, test := range tests {
+		matched, matchedZones := notifier.match(&test.rule, detected, frameSize)
+		if fmt.Sprint(matched) != fmt.Sprint(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, matched)
+		}
+		if fmt.Sprint(matchedZones) != fmt.Sprint(test.zones) {
+			t.Errorf("%s: expected zones %v, got %v", test.name, test.zones, matchedZones)
+		}
+	}
+}
+
+func TestWebhookNotifierCooldown(t *testing.T) {
+	server, requests := newWebhookTestServer(t)
+	settings := &WebhookSettings{
+		Rules: []WebhookRule{{Name: "any", URL: server.URL, CooldownSec: 10}},
+	}
+	notifier := newTestWebhookNotifier(t, settings, nil)
+
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	detected := []*DetectedObject{{Rect: image.Rect(10, 10, 30, 60), ClassName: "car", Confidence: 0.5}}
+	for _, offset := range []time.Duration{0, 5 * time.Second, 11 * time.Second} {
+		if err := notifier.Consume(newWebhookTestFrame(t, start.Add(offset)), detected); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := notifier.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(requests()); n != 2 {
+		t.Errorf("expected 2 notifications, got %d", n)
+	}
+}
+
+func TestWebhookSnapshotRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"cam_rule_1.jpg":   -48 * time.Hour,
+		"cam_rule_2.jpg":   -3 * time.Hour,
+		"cam_rule_3.jpg":   -2 * time.Hour,
+		"cam_rule_4.jpg":   -1 * time.Hour,
+		"other_rule_1.jpg": -48 * time.Hour,
+		"cam_notes.txt":    -48 * time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(age), now.Add(age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifier := &WebhookNotifier{
+		settings: &WebhookSettings{
+			SnapshotDirectory:   dir,
+			SnapshotMaxFiles:    2,
+			SnapshotMaxAgeHours: 24,
+		},
+		streamName: "cam",
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	notifier.applyRetention()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, entry := range entries {
+		kept = append(kept, entry.Name())
+	}
+	sort.Strings(kept)
+	expected := []string{"cam_notes.txt", "cam_rule_3.jpg", "cam_rule_4.jpg", "other_rule_1.jpg"}
+	if fmt.Sprint(kept) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, kept)
+	}
+}
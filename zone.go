@@ -0,0 +1,77 @@
+package ml
+
+import (
+	"fmt"
+	"image"
+)
+
+// ZoneSettings Named region of frame. Points of polygon are normalized to [0..1] by frame size
+type ZoneSettings struct {
+	Name   string       `json:"name"`
+	Points [][2]float64 `json:"points"`
+	// Point of bounding box which has to be inside of zone: "bottom" (bottom-center, default) or "center"
+	Anchor string `json:"anchor"`
+}
+
+// Zone Polygon region used to check whether detected objects are inside of it
+type Zone struct {
+	Name    string
+	Anchor  string
+	Polygon Polygon
+}
+
+// NewZones Creates zones from settings
+func NewZones(settings []ZoneSettings) ([]*Zone, error) {
+	zones := make([]*Zone, 0, len(settings))
+	for _, zs := range settings {
+		if zs.Name == "" {
+			return nil, fmt.Errorf("zone name is empty")
+		}
+		if len(zs.Points) < 3 {
+			return nil, fmt.Errorf("zone '%s' must have at least 3 points", zs.Name)
+		}
+		anchor := zs.Anchor
+		if anchor != "center" {
+			anchor = "bottom"
+		}
+		zones = append(zones, &Zone{Name: zs.Name, Anchor: anchor, Polygon: zs.Points})
+	}
+	return zones, nil
+}
+
+// Contains Checks whether anchor point of rectangle is inside of zone. Rectangle is given in coordinates of frame of provided size
+func (z *Zone) Contains(r image.Rectangle, frameSize image.Point) bool {
+	x := float64(r.Min.X+r.Max.X) / 2
+	y := float64(r.Max.Y)
+	if z.Anchor == "center" {
+		y = float64(r.Min.Y+r.Max.Y) / 2
+	}
+	return z.Polygon.Contains(x/float64(frameSize.X), y/float64(frameSize.Y))
+}
+
+// zoneNames returns names of zones containing rectangle
+func zoneNames(zones []*Zone, r image.Rectangle, frameSize image.Point) []string {
+	var names []string
+	for _, zone := range zones {
+		if zone.Contains(r, frameSize) {
+			names = append(names, zone.Name)
+		}
+	}
+	return names
+}
+
+// Polygon List of normalized points
+type Polygon [][2]float64
+
+// Contains Checks whether normalized point is inside of polygon (ray casting)
+func (p Polygon) Contains(x, y float64) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi := p[i][0], p[i][1]
+		xj, yj := p[j][0], p[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}